@@ -0,0 +1,227 @@
+// Package assets copies the static files of a site to its output dir,
+// keeping their paths, and fingerprints the files browsers may cache for
+// good with a hash of their content in their name.
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Config is the "assets" section of the config file.
+type Config struct {
+	// Fingerprint are the patterns of the files copied a second time with
+	// the hash of their content in their name, like *.css, matched against
+	// the path of the file in the static dir and against its base name
+	Fingerprint []string `json:"fingerprint"`
+	// Integrity computes the Subresource Integrity hash of the
+	// fingerprinted files
+	Integrity bool `json:"integrity"`
+}
+
+// Asset is a file of the static dir.
+type Asset struct {
+	// Path is the path of the file in the static dir, with slashes
+	Path string
+	// URL is the path of the copy to link to, relative to the site root:
+	// the fingerprinted copy like style.3f2a1c0b.css, or Path
+	URL string
+	// Hash is the hex encoded sha256 of the content
+	Hash string
+	// Integrity is the Subresource Integrity hash of a fingerprinted file,
+	// like sha384-<base64>, when Config.Integrity is set
+	Integrity string
+	// Source is the file in the static dir
+	Source string
+	// Size is the size of Source
+	Size int
+	// Data is the content to write instead of copying Source, when the
+	// transform of Scan changed it or the asset was generated
+	Data []byte
+}
+
+// Outputs returns the paths the asset is copied to relative to the output
+// dir, Path and the fingerprinted URL if there is one.
+func (a Asset) Outputs() []string {
+	if a.URL == a.Path {
+		return []string{a.Path}
+	}
+	return []string{a.Path, a.URL}
+}
+
+// Pipeline holds the assets of a static dir.
+type Pipeline struct {
+	Dir       string
+	assets    map[string]Asset
+	config    Config
+	transform Transform
+}
+
+// Transform returns the content to write for the file name of the static
+// dir, like a minified stylesheet, or data itself.
+type Transform func(name string, data []byte) []byte
+
+// Scan hashes every file below dir, after transform if it is not nil, and
+// names the fingerprinted copies.
+func Scan(dir string, config Config, transform Transform) (*Pipeline, error) {
+	for _, pattern := range config.Fingerprint {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("fingerprint pattern %q: %w", pattern, err)
+		}
+	}
+	p := &Pipeline{Dir: dir, assets: make(map[string]Asset), config: config, transform: transform}
+	err := filepath.WalkDir(dir, func(source string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, source)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		p.add(Asset{Path: filepath.ToSlash(rel), Source: source}, data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Add adds a file generated by the build at name, like a stylesheet,
+// replacing the file of the static dir of the same name. It is
+// transformed and fingerprinted like the files of the static dir.
+func (p *Pipeline) Add(name string, data []byte) {
+	p.add(Asset{Path: strings.TrimPrefix(path.Clean("/"+name), "/"), Data: data}, data)
+}
+
+func (p *Pipeline) add(asset Asset, data []byte) {
+	asset.Size = len(data)
+	if p.transform != nil {
+		if transformed := p.transform(asset.Path, data); !bytes.Equal(transformed, data) {
+			asset.Data = transformed
+			data = transformed
+		}
+	}
+	sum := sha256.Sum256(data)
+	asset.Hash = hex.EncodeToString(sum[:])
+	asset.URL = asset.Path
+	if fingerprinted(asset.Path, p.config.Fingerprint) {
+		asset.URL = Fingerprint(asset.Path, asset.Hash)
+		if p.config.Integrity {
+			integrity := sha512.Sum384(data)
+			asset.Integrity = "sha384-" + base64.StdEncoding.EncodeToString(integrity[:])
+		}
+	}
+	p.assets[asset.Path] = asset
+}
+
+func fingerprinted(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Fingerprint returns name with the first 8 characters of hash before its
+// extension, like css/style.3f2a1c0b.css.
+func Fingerprint(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash[:min(len(hash), 8)] + ext
+}
+
+// Lookup returns the asset at path in the static dir. A nil Pipeline has
+// no assets.
+func (p *Pipeline) Lookup(name string) (Asset, bool) {
+	if p == nil {
+		return Asset{}, false
+	}
+	asset, ok := p.assets[strings.TrimPrefix(path.Clean("/"+name), "/")]
+	return asset, ok
+}
+
+// Assets returns every asset, sorted by path.
+func (p *Pipeline) Assets() []Asset {
+	if p == nil {
+		return nil
+	}
+	assets := make([]Asset, 0, len(p.assets))
+	for _, asset := range p.assets {
+		assets = append(assets, asset)
+	}
+	slices.SortFunc(assets, func(a, b Asset) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return assets
+}
+
+// Key changes whenever the URL or the integrity of a fingerprinted asset
+// does, so that the pages linking to them are rendered again.
+func (p *Pipeline) Key() string {
+	var key strings.Builder
+	for _, asset := range p.Assets() {
+		if asset.URL != asset.Path {
+			fmt.Fprintf(&key, "%s %s %s\n", asset.URL, asset.Hash, asset.Integrity)
+		}
+	}
+	return key.String()
+}
+
+// CopyFile copies src to dst, creating its directory, unless dst already
+// has the size and the modification time of src. The copy gets the
+// modification time of src. It reports whether it copied the file.
+func CopyFile(src, dst string) (copied bool, err error) {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return false, err
+	}
+	dstInfo, err := os.Stat(dst)
+	if err == nil && dstInfo.Size() == srcInfo.Size() && dstInfo.ModTime().Equal(srcInfo.ModTime()) {
+		return false, nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer srcFile.Close()
+	err = os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+	if err != nil {
+		return false, err
+	}
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return false, err
+	}
+	_, err = io.Copy(dstFile, srcFile)
+	if closeErr := dstFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, os.Chtimes(dst, srcInfo.ModTime(), srcInfo.ModTime())
+}
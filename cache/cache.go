@@ -0,0 +1,253 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// version is bumped whenever the layout of the cache directory or the
+// meaning of a key changes, which invalidates every existing entry.
+const version = 1
+
+const manifestFileName = "manifest.json"
+
+type manifest struct {
+	Version int               `json:"version"`
+	Outputs map[string]string `json:"outputs"`
+}
+
+// BuildCache remembers, for every generated file, the hash of the inputs
+// it was rendered from, and keeps the converted HTML of every post keyed
+// by the hash of its source. A nil *BuildCache is valid and caches nothing.
+type BuildCache struct {
+	Dir string
+
+	mu          sync.Mutex
+	outputs     map[string]string
+	touched     map[string]bool
+	htmlTouched map[string]bool
+	rendered    int
+}
+
+// Open loads the cache stored in dir. A missing or stale cache is not an
+// error, it just starts out empty.
+func Open(dir string) (*BuildCache, error) {
+	c := &BuildCache{
+		Dir:         dir,
+		outputs:     make(map[string]string),
+		touched:     make(map[string]bool),
+		htmlTouched: make(map[string]bool),
+	}
+	data, err := os.ReadFile(filepath.Join(dir, manifestFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil || m.Version != version {
+		return c, nil
+	}
+	if m.Outputs != nil {
+		c.outputs = m.Outputs
+	}
+	return c, nil
+}
+
+// Hash returns the hex encoded sha256 of all parts, each part length
+// prefixed so that ("ab", "c") and ("a", "bc") hash differently.
+func Hash(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		var size [8]byte
+		binary.LittleEndian.PutUint64(size[:], uint64(len(part)))
+		h.Write(size[:])
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashDir hashes the names and contents of every file below dir.
+func HashDir(dir string) (string, error) {
+	parts := []string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, filepath.ToSlash(path), string(data))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return Hash(parts...), nil
+}
+
+// Fresh reports whether output exists and was last rendered from key. A
+// fresh output counts as produced by this build and will not be pruned.
+func (c *BuildCache) Fresh(output, key string) bool {
+	if c == nil {
+		return false
+	}
+	output = filepath.Clean(output)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.outputs[output] != key {
+		return false
+	}
+	if _, err := os.Stat(output); err != nil {
+		return false
+	}
+	c.touched[output] = true
+	return true
+}
+
+// Record remembers that output has been rendered from key.
+func (c *BuildCache) Record(output, key string) {
+	if c == nil {
+		return
+	}
+	output = filepath.Clean(output)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.outputs[output] = key
+	c.touched[output] = true
+	c.rendered++
+}
+
+// Rendered returns the number of outputs recorded so far, which is the
+// number of pages that were not fresh.
+func (c *BuildCache) Rendered() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rendered
+}
+
+// Keep marks every output below dir as produced by this build, for when a
+// whole part of the build is skipped because none of its inputs changed.
+func (c *BuildCache) Keep(dir string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for output := range c.outputs {
+		rel, err := filepath.Rel(filepath.Clean(dir), output)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			c.touched[output] = true
+		}
+	}
+}
+
+func (c *BuildCache) htmlPath(sourceHash string) string {
+	return filepath.Join(c.Dir, "html", sourceHash+".html")
+}
+
+// HTML returns the converted markdown previously stored for sourceHash.
+func (c *BuildCache) HTML(sourceHash string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.htmlPath(sourceHash))
+	if err != nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	c.htmlTouched[sourceHash] = true
+	c.mu.Unlock()
+	return data, true
+}
+
+// StoreHTML keeps the converted markdown of the source hashed to sourceHash.
+func (c *BuildCache) StoreHTML(sourceHash string, html []byte) error {
+	if c == nil {
+		return nil
+	}
+	path := c.htmlPath(sourceHash)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.htmlTouched[sourceHash] = true
+	c.mu.Unlock()
+	return os.WriteFile(path, html, 0660)
+}
+
+// Prune deletes every output of the previous build that was neither
+// rendered nor found fresh in this one, along with the directories it
+// leaves empty, and forgets the converted HTML of sources that are gone.
+// It returns the removed outputs in sorted order.
+func (c *BuildCache) Prune() ([]string, error) {
+	if c == nil {
+		return nil, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	removed := []string{}
+	for output := range c.outputs {
+		if c.touched[output] {
+			continue
+		}
+		delete(c.outputs, output)
+		err := os.Remove(output)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		}
+		removed = append(removed, output)
+		for dir := filepath.Dir(output); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	sort.Strings(removed)
+
+	entries, err := os.ReadDir(filepath.Join(c.Dir, "html"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return removed, err
+	}
+	for _, entry := range entries {
+		sourceHash := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if !c.htmlTouched[sourceHash] {
+			os.Remove(filepath.Join(c.Dir, "html", entry.Name()))
+		}
+	}
+	return removed, nil
+}
+
+// Save writes the manifest back to the cache directory.
+func (c *BuildCache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(manifest{Version: version, Outputs: c.outputs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.Dir, manifestFileName), data, 0660)
+}
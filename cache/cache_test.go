@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFresh(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "public", "a", "index.html")
+	tests := []struct {
+		name   string
+		key    string
+		remove bool
+		want   bool
+	}{
+		{"same key", "k1", false, true},
+		{"changed key", "k2", false, false},
+		{"missing output", "k1", true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			writeFile(t, output, "a")
+			c, err := Open(cacheDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Fresh(output, "k1") {
+				t.Fatal("an empty cache has a fresh output")
+			}
+			c.Record(output, "k1")
+			if err := c.Save(); err != nil {
+				t.Fatal(err)
+			}
+			if test.remove {
+				os.Remove(output)
+			}
+
+			reloaded, err := Open(cacheDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := reloaded.Fresh(output, test.key); got != test.want {
+				t.Errorf("Fresh() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestOpenStale(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "index.html")
+	writeFile(t, output, "a")
+	for _, manifest := range []string{`{"version": 0, "outputs": {"` + output + `": "k"}}`, "not json"} {
+		writeFile(t, filepath.Join(dir, manifestFileName), manifest)
+		c, err := Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Fresh(output, "k") {
+			t.Errorf("the outputs of manifest %q are fresh", manifest)
+		}
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *BuildCache
+	c.Record("a", "k")
+	if c.Fresh("a", "k") || c.Rendered() != 0 {
+		t.Error("a nil cache remembers outputs")
+	}
+	if _, ok := c.HTML("h"); ok {
+		t.Error("a nil cache has HTML")
+	}
+	if removed, err := c.Prune(); removed != nil || err != nil {
+		t.Errorf("Prune() = %v, %v", removed, err)
+	}
+	if err := c.Save(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	public := filepath.Join(dir, "public")
+	kept := filepath.Join(public, "kept", "index.html")
+	rendered := filepath.Join(public, "posts", "new", "index.html")
+	stale := filepath.Join(public, "posts", "old", "deep", "index.html")
+	sibling := filepath.Join(public, "posts", "old", "image.png")
+	gone := filepath.Join(public, "gone", "index.html")
+	skipped := filepath.Join(public, "admin", "index.html")
+	for _, path := range []string{kept, rendered, stale, sibling, skipped} {
+		writeFile(t, path, path)
+	}
+
+	cacheDir := filepath.Join(dir, "cache")
+	c, err := Open(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, output := range []string{kept, rendered, stale, gone, skipped} {
+		c.Record(output, "k1")
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the next build renders one page again, finds one fresh and skips
+	// the admin copy whole
+	c, err = Open(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Fresh(kept, "k1") {
+		t.Fatal("kept is not fresh")
+	}
+	c.Record(rendered, "k2")
+	c.Keep(filepath.Join(public, "admin"))
+	if c.Rendered() != 1 {
+		t.Errorf("Rendered() = %d, want 1", c.Rendered())
+	}
+	removed, err := c.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{gone, stale}; !slices.Equal(removed, want) {
+		t.Errorf("Prune() = %v, want %v", removed, want)
+	}
+	for _, path := range []string{kept, rendered, sibling, skipped} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %v", path, err)
+		}
+	}
+	// the directories the stale output leaves empty go, the one still
+	// holding another file stays
+	if _, err := os.Stat(filepath.Join(public, "posts", "old", "deep")); !os.IsNotExist(err) {
+		t.Errorf("the empty dir of the stale output is left: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(public, "posts", "old")); err != nil {
+		t.Errorf("the dir holding another file was removed: %v", err)
+	}
+
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	c, err = Open(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed, _ := c.Prune(); len(removed) != 3 {
+		t.Errorf("a build producing nothing prunes %v, want the 3 outputs left", removed)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	cacheDir := t.TempDir()
+	c, err := Open(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.HTML("a"); ok {
+		t.Fatal("an empty cache has HTML")
+	}
+	for hash, html := range map[string]string{"a": "<p>A</p>", "b": "<p>B</p>"} {
+		if err := c.StoreHTML(hash, []byte(html)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the next build reads a, and b, whose source is gone, is forgotten
+	c, err = Open(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html, ok := c.HTML("a")
+	if !ok || string(html) != "<p>A</p>" {
+		t.Errorf("HTML(a) = %q, %v, want the stored HTML", html, ok)
+	}
+	if _, err := c.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	c, err = Open(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.HTML("a"); !ok {
+		t.Error("the HTML read by the last build was pruned")
+	}
+	if _, ok := c.HTML("b"); ok {
+		t.Error("the HTML of a source gone was kept")
+	}
+}
+
+func TestHash(t *testing.T) {
+	if Hash("ab", "c") == Hash("a", "bc") {
+		t.Error(`Hash("ab", "c") == Hash("a", "bc")`)
+	}
+	if Hash("a") != Hash("a") {
+		t.Error("Hash is not deterministic")
+	}
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,95 +9,37 @@ import (
 	"strings"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/mr-destructive/mr-destructive.github.io/frontmatter"
+	models "github.com/mr-destructive/mr-destructive.github.io/models"
 )
 
-// FrontMatter represents the front matter of a post
-type FrontMatter struct {
-	Title       string                 `json:"title" yaml:"title"`
-	Description string                 `json:"description" yaml:"description"`
-	Status      string                 `json:"status" yaml:"status"`
-	Type        string                 `json:"type" yaml:"type"`
-	Date        string                 `json:"date" yaml:"date"`
-	Slug        string                 `json:"slug" yaml:"slug"`
-	Tags        []string               `json:"tags" yaml:"tags"`
-	ImageURL    string                 `json:"image_url" yaml:"image_url"`
-	Extras      map[string]interface{} `json:",inline" yaml:",inline"`
-}
-
-// CleanFrontmatter cleans up inconsistent frontmatter in posts
-func CleanFrontmatter(content []byte) ([]byte, error) {
-	// Try to detect JSON front matter
-	jsonSeparator := []byte("}\n\n")
-	jsonIndex := indexOfBytes(content, jsonSeparator)
-
-	if jsonIndex != -1 {
-		frontmatterBytes := content[:jsonIndex+1] // Keep closing brace
-		contentBytes := content[jsonIndex+2:]     // Skip the separator
-
-		// Parse frontmatter
-		var frontmatterObj FrontMatter
-		tempMap := make(map[string]interface{})
-		if err := json.Unmarshal(frontmatterBytes, &tempMap); err == nil {
-			// Extract known fields into the struct
-			if err := json.Unmarshal(frontmatterBytes, &frontmatterObj); err != nil {
-				return nil, fmt.Errorf("error parsing JSON front matter: %v", err)
-			}
-
-			// Clean the frontmatter
-			frontmatterObj = cleanFrontmatterFields(frontmatterObj)
-
-			// Convert back to JSON
-			cleanedFrontmatter, err := json.Marshal(frontmatterObj)
-			if err != nil {
-				return nil, fmt.Errorf("error marshaling cleaned frontmatter: %v", err)
-			}
-
-			// Combine cleaned frontmatter with content
-			result := append(cleanedFrontmatter, []byte("\n\n")...)
-			result = append(result, contentBytes...)
-			return result, nil
-		}
+// FixFrontmatter cleans up inconsistent frontmatter in posts, keeping
+// the format it is written in
+func FixFrontmatter(content []byte) ([]byte, error) {
+	document, err := frontmatter.Split(content)
+	if errors.Is(err, frontmatter.ErrNoFrontMatter) {
+		return content, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	frontmatterObj, err := frontmatter.Decode(document)
+	if err != nil {
+		return nil, err
 	}
 
-	// Try to detect YAML front matter
-	yamlSeparator := []byte("---\n\n")
-	yamlIndex := indexOfBytes(content, yamlSeparator)
-
-	if yamlIndex != -1 {
-		frontmatterBytes := content[:yamlIndex]
-		contentBytes := content[yamlIndex+len(yamlSeparator):]
-
-		// Parse frontmatter
-		var frontmatterObj FrontMatter
-		tempMap := make(map[string]interface{})
-		if err := yaml.Unmarshal(frontmatterBytes, &tempMap); err == nil {
-			// Extract known fields into the struct
-			if err := yaml.Unmarshal(frontmatterBytes, &frontmatterObj); err != nil {
-				return nil, fmt.Errorf("error parsing YAML front matter: %v", err)
-			}
-
-			// Clean the frontmatter
-			frontmatterObj = cleanFrontmatterFields(frontmatterObj)
+	// Clean the frontmatter
+	frontmatterObj = cleanFrontmatterFields(frontmatterObj)
 
-			// Convert back to YAML
-			cleanedFrontmatter, err := yaml.Marshal(frontmatterObj)
-			if err != nil {
-				return nil, fmt.Errorf("error marshaling cleaned frontmatter: %v", err)
-			}
-
-			// Combine cleaned frontmatter with content
-			result := append(cleanedFrontmatter, []byte("---\n\n")...)
-			result = append(result, contentBytes...)
-			return result, nil
-		}
+	cleaned, err := frontmatter.Encode(document, frontmatterObj)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling cleaned frontmatter: %v", err)
 	}
-
-	return content, nil
+	return cleaned, nil
 }
 
 // cleanFrontmatterFields cleans individual frontmatter fields
-func cleanFrontmatterFields(fm FrontMatter) FrontMatter {
+func cleanFrontmatterFields(fm models.FrontMatter) models.FrontMatter {
 	// Ensure title is present
 	if fm.Title == "" {
 		fm.Title = "Untitled Post"
@@ -172,30 +114,20 @@ func cleanFrontmatterFields(fm FrontMatter) FrontMatter {
 func slugify(input string) string {
 	// Convert to lowercase
 	slug := strings.ToLower(input)
-	
+
 	// Replace spaces and special characters with hyphens
 	reg := regexp.MustCompile("[^a-z0-9]+")
 	slug = reg.ReplaceAllString(slug, "-")
-	
+
 	// Trim leading/trailing hyphens
 	slug = strings.Trim(slug, "-")
-	
+
 	// If slug is empty, use a default
 	if slug == "" {
 		slug = "untitled"
 	}
-	
-	return slug
-}
 
-// indexOfBytes finds the index of a byte sequence in a byte slice
-func indexOfBytes(slice, sub []byte) int {
-	for i := 0; i <= len(slice)-len(sub); i++ {
-		if string(slice[i:i+len(sub)]) == string(sub) {
-			return i
-		}
-	}
-	return -1
+	return slug
 }
 
 // processFile cleans the frontmatter of a single file
@@ -207,7 +139,7 @@ func processFile(filePath string) error {
 	}
 
 	// Clean frontmatter
-	cleanedContent, err := CleanFrontmatter(content)
+	cleanedContent, err := FixFrontmatter(content)
 	if err != nil {
 		return fmt.Errorf("error cleaning frontmatter in file %s: %v", filePath, err)
 	}
@@ -240,25 +172,22 @@ func processDirectory(dirPath string) error {
 	})
 }
 
-func main() {
-	// Process all posts directories
-	dirs := []string{
-		"posts/newsletter",
-		"posts/posts",
-		"posts/projects",
-		"posts/thoughts",
-		"posts/til",
-		"posts/work",
-		"posts/sqlog",
+// frontmatterFixCommand rewrites the front matter of every post with the
+// missing fields filled in and the dates and types normalized.
+func frontmatterFixCommand(args []string) error {
+	flags, global := commandFlags("frontmatter fix", "")
+	flags.Parse(args)
+	config, err := global.LoadConfig()
+	if err != nil {
+		return err
 	}
 
-	for _, dir := range dirs {
-		fmt.Printf("Processing directory: %s\n", dir)
-		err := processDirectory(dir)
-		if err != nil {
-			fmt.Printf("Error processing directory %s: %v\n", dir, err)
-		}
+	fmt.Printf("Processing directory: %s\n", config.Blog.PostsDir)
+	err = processDirectory(config.Blog.PostsDir)
+	if err != nil {
+		return fmt.Errorf("processing directory %s: %w", config.Blog.PostsDir, err)
 	}
 
 	fmt.Println("Frontmatter cleanup completed!")
-}
\ No newline at end of file
+	return nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mr-destructive/mr-destructive.github.io/frontmatter"
+	models "github.com/mr-destructive/mr-destructive.github.io/models"
+	"github.com/mr-destructive/mr-destructive.github.io/plugins"
+)
+
+// errBuildFailed is returned by commands that already printed what went
+// wrong, it only sets the exit status.
+var errBuildFailed = errors.New("build failed")
+
+type Command struct {
+	// Name is the words selecting the command, like "db sync".
+	Name  string
+	Args  string
+	Short string
+	Run   func(args []string) error
+}
+
+var commands = []Command{
+	{"build", "", "Build the site into the output dir", buildCommand},
+	{"serve", "", "Serve the site and rebuild it on every change", serveCommand},
+	{"new", "<type> <title>", "Create a draft post of the given type", newCommand},
+	{"clean", "", "Remove the output dir and the build cache", cleanCommand},
+	{"check", "", "Build the site without writing it and report every problem", checkCommand},
+	{"db init", "", "Create the sqlite database and load the posts into it", dbInitCommand},
+	{"db sync", "", "Export the posts written in the editor into the posts dir", dbSyncCommand},
+	{"frontmatter fix", "", "Fill in and normalize the front matter of every post", frontmatterFixCommand},
+	{"editor", "", "Serve the post editor", editorCommand},
+	{"schema", "", "Print the JSON Schema of the config file", schemaCommand},
+}
+
+// offlineUsage is the usage of the -offline flag of the commands that
+// build the site.
+const offlineUsage = "embed links with the cached oEmbed responses only, and a stand-in without the network for the others"
+
+// GlobalFlags are the flags every command accepts.
+type GlobalFlags struct {
+	ConfigPath string
+	OutputDir  string
+	BaseURL    string
+}
+
+// commandFlags returns the flag set of the command name, with the global
+// flags already defined on it.
+func commandFlags(name, args string) (*flag.FlagSet, *GlobalFlags) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	global := &GlobalFlags{}
+	flags.StringVar(&global.ConfigPath, "config", models.SSG_CONFIG_FILE_NAME, "path to the config file")
+	flags.StringVar(&global.OutputDir, "output-dir", "", "directory to write the site to, instead of blog.output_dir")
+	flags.StringVar(&global.BaseURL, "base-url", "", "URL the site is served from, instead of blog.base_url")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: ssg %s [flags] %s\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags, global
+}
+
+// LoadConfig reads the config file and applies the flags overriding it.
+func (g *GlobalFlags) LoadConfig() (models.SSG_CONFIG, error) {
+	config, err := models.LoadConfig(g.ConfigPath)
+	if err != nil {
+		return config, err
+	}
+	if g.OutputDir != "" {
+		config.Blog.OutputDir = g.OutputDir
+	}
+	if g.BaseURL != "" {
+		config.Blog.BaseUrl = g.BaseURL
+	}
+	return config, nil
+}
+
+// CacheDir returns the build cache directory, kept next to the config.
+func (g *GlobalFlags) CacheDir() string {
+	return filepath.Join(filepath.Dir(g.ConfigPath), models.SSG_CACHE_DIR)
+}
+
+// EmbedCacheDir returns the directory the oEmbed responses are kept in,
+// next to the config and apart from the build cache, so that clean keeps
+// them for offline builds.
+func (g *GlobalFlags) EmbedCacheDir() string {
+	return filepath.Join(filepath.Dir(g.ConfigPath), models.SSG_EMBED_DIR)
+}
+
+func main() {
+	args := os.Args[1:]
+	// the flags of the commands would take these for build -h
+	if len(args) > 0 && slices.Contains([]string{"-h", "-help", "--help", "help"}, args[0]) {
+		usage()
+		return
+	}
+	// building is what running without a command always did
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{"build"}, args...)
+	}
+	if args[0] == "dev" {
+		args[0] = "serve"
+	}
+	for _, command := range commands {
+		words := strings.Fields(command.Name)
+		if len(args) < len(words) || !slices.Equal(args[:len(words)], words) {
+			continue
+		}
+		err := command.Run(args[len(words):])
+		if errors.Is(err, errBuildFailed) {
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ssg %s: %v\n", command.Name, err)
+			os.Exit(1)
+		}
+		return
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: ssg <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, command := range commands {
+		fmt.Fprintf(os.Stderr, "  %-36s %s\n", strings.TrimSpace(command.Name+" "+command.Args), command.Short)
+	}
+	fmt.Fprintln(os.Stderr, "\nrun ssg <command> -h for the flags of a command")
+}
+
+func buildCommand(args []string) error {
+	flags, global := commandFlags("build", "")
+	keepGoing := flags.Bool("keep-going", false, "build every page that can be built instead of stopping at the first failing plugin")
+	offline := flags.Bool("offline", false, offlineUsage)
+	flags.Parse(args)
+	config, err := global.LoadConfig()
+	if err != nil {
+		return err
+	}
+	diagnostics := Build(config, BuildOptions{
+		KeepGoing:     *keepGoing,
+		CacheDir:      global.CacheDir(),
+		EmbedCacheDir: global.EmbedCacheDir(),
+		Offline:       *offline,
+	})
+	if len(diagnostics) > 0 {
+		PrintDiagnostics(diagnostics)
+		return errBuildFailed
+	}
+	return nil
+}
+
+func serveCommand(args []string) error {
+	flags, global := commandFlags("serve", "")
+	host := flags.String("host", "", "host to listen on, instead of the server plugin option")
+	port := flags.Int("port", 0, "port to listen on, instead of the server plugin option")
+	offline := flags.Bool("offline", false, offlineUsage)
+	flags.Parse(args)
+	config, err := global.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	entry := models.PluginConfig{Name: "server"}
+	for _, configured := range config.Plugins {
+		if configured.Name == "server" {
+			entry = configured
+		}
+	}
+	plugin, err := LoadPlugin(entry)
+	if err != nil {
+		return err
+	}
+	server := plugin.(*ServerPlugin)
+	server.Flags = global
+	server.Offline = *offline
+	if *host != "" {
+		server.Host = *host
+	}
+	if *port != 0 {
+		server.Port = *port
+	}
+	pluginManager := PluginManager{}
+	pluginManager.Register(server)
+	err = pluginManager.ExecuteAll(&models.SSG{Config: config})
+	if err != nil {
+		PrintDiagnostics(pluginManager.Diagnostics)
+		return errBuildFailed
+	}
+	return nil
+}
+
+func newCommand(args []string) error {
+	flags, global := commandFlags("new", "<type> <title>")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	postType, title := flags.Arg(0), flags.Arg(1)
+	config, err := global.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Blog.PagesConfig[postType]; !ok {
+		types := []string{}
+		for known := range config.Blog.PagesConfig {
+			types = append(types, known)
+		}
+		sort.Strings(types)
+		return fmt.Errorf("unknown post type %q, the config has %s", postType, strings.Join(types, ", "))
+	}
+
+	front := models.FrontMatter{
+		Title:  title,
+		Status: "draft",
+		Type:   postType,
+		Date:   time.Now().Format("2006-01-02"),
+		Slug:   plugins.Slugify(title),
+		Tags:   []string{},
+	}
+	body := fmt.Sprintf("\n## %s\n\n", title)
+	content, err := frontmatter.Encode(frontmatter.Document{Format: frontmatter.JSON, Fenced: true, Body: []byte(body)}, front)
+	if err != nil {
+		return err
+	}
+	postPath := filepath.Join(config.Blog.PostsDir, postType, front.Slug+".md")
+	if _, err := os.Stat(postPath); err == nil {
+		return fmt.Errorf("%s already exists", postPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(postPath), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.WriteFile(postPath, content, 0660); err != nil {
+		return err
+	}
+	fmt.Println("Created", postPath)
+	return nil
+}
+
+func cleanCommand(args []string) error {
+	flags, global := commandFlags("clean", "")
+	flags.Parse(args)
+	config, err := global.LoadConfig()
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{config.Blog.OutputDir, global.CacheDir()} {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		fmt.Println("Removed", dir)
+	}
+	return nil
+}
+
+// checkCommand builds the whole site into a temporary directory, bypassing
+// the cache so that every page is rendered, and reports every problem.
+func checkCommand(args []string) error {
+	flags, global := commandFlags("check", "")
+	offline := flags.Bool("offline", false, offlineUsage)
+	flags.Parse(args)
+	config, err := global.LoadConfig()
+	if err != nil {
+		return err
+	}
+	outputDir, err := os.MkdirTemp("", "ssg-check-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(outputDir)
+	config.Blog.OutputDir = outputDir
+	// the embeds are still cached, they do not change with the posts
+	diagnostics := Build(config, BuildOptions{KeepGoing: true, EmbedCacheDir: global.EmbedCacheDir(), Offline: *offline})
+	if len(diagnostics) > 0 {
+		PrintDiagnostics(diagnostics)
+		return errBuildFailed
+	}
+	fmt.Println("No problems found")
+	return nil
+}
+
+func editorCommand(args []string) error {
+	flags, _ := commandFlags("editor", "")
+	addr := flags.String("addr", ":8081", "address to listen on")
+	flags.Parse(args)
+	http.HandleFunc("/editor", plugins.PostHandler)
+	fmt.Println("Listening on", *addr)
+	return http.ListenAndServe(*addr, nil)
+}
+
+func schemaCommand(args []string) error {
+	flags, _ := commandFlags("schema", "")
+	flags.Parse(args)
+	schema, err := json.MarshalIndent(models.ConfigSchema(), "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(schema))
+	return nil
+}
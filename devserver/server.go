@@ -0,0 +1,164 @@
+package devserver
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ReloadPath is the event stream the injected script listens on.
+const ReloadPath = "/__livereload"
+
+const reloadScript = `<script>
+(function () {
+  var source = new EventSource("` + ReloadPath + `");
+  source.onmessage = function (event) {
+    if (event.data === "reload") {
+      location.reload();
+    }
+  };
+})();
+</script>
+`
+
+const overlayTemplate = `<div id="__ssg-overlay" style="position:fixed;inset:0;z-index:2147483647;overflow:auto;margin:0;padding:2em;background:rgba(20,20,20,.95);color:#f5f5f5;font:14px/1.5 monospace;">
+<h2 style="margin-top:0;color:#ff6b6b;">Build failed</h2>
+<pre style="white-space:pre-wrap;">%s</pre>
+<p style="color:#aaa;">The page reloads once the build succeeds.</p>
+</div>
+`
+
+// Server serves the output directory of the dev build. Every HTML page it
+// serves gets a script that reloads the page after each rebuild, and while
+// the last build is failing an overlay showing what went wrong.
+type Server struct {
+	Dir string
+
+	mu      sync.Mutex
+	clients map[chan string]bool
+	failure string
+	files   http.Handler
+}
+
+func NewServer(dir string) *Server {
+	return &Server{
+		Dir:     dir,
+		clients: make(map[chan string]bool),
+		files:   http.FileServer(http.Dir(dir)),
+	}
+}
+
+// Reload records the errors of the build that just finished, nil if it
+// succeeded, and tells every open page to reload.
+func (s *Server) Reload(errs []error) {
+	lines := []string{}
+	for _, err := range errs {
+		lines = append(lines, err.Error())
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failure = strings.Join(lines, "\n")
+	for client := range s.clients {
+		select {
+		case client <- "reload":
+		default:
+		}
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == ReloadPath {
+		s.events(w, r)
+		return
+	}
+	file := s.htmlFile(r.URL.Path)
+	if file == "" {
+		s.files.ServeHTTP(w, r)
+		return
+	}
+	page, err := os.ReadFile(file)
+	if err != nil {
+		s.mu.Lock()
+		failure := s.failure
+		s.mu.Unlock()
+		if failure == "" {
+			s.files.ServeHTTP(w, r)
+			return
+		}
+		page = []byte("<!DOCTYPE html>\n<html><body></body></html>\n")
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(s.inject(page))
+}
+
+// htmlFile returns the file an HTML page request is served from, or "" if
+// the request is for anything else.
+func (s *Server) htmlFile(urlPath string) string {
+	name := path.Clean("/" + urlPath)
+	if strings.HasSuffix(urlPath, "/") {
+		name = path.Join(name, "index.html")
+	}
+	file := filepath.Join(s.Dir, filepath.FromSlash(name))
+	if info, err := os.Stat(file); err == nil && info.IsDir() {
+		return ""
+	}
+	if path.Ext(name) != ".html" {
+		return ""
+	}
+	return file
+}
+
+func (s *Server) inject(page []byte) []byte {
+	s.mu.Lock()
+	failure := s.failure
+	s.mu.Unlock()
+	snippet := reloadScript
+	if failure != "" {
+		snippet = fmt.Sprintf(overlayTemplate, html.EscapeString(failure)) + snippet
+	}
+	i := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if i < 0 {
+		return append(page, snippet...)
+	}
+	injected := make([]byte, 0, len(page)+len(snippet))
+	injected = append(injected, page[:i]...)
+	injected = append(injected, snippet...)
+	return append(injected, page[i:]...)
+}
+
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	client := make(chan string, 1)
+	s.mu.Lock()
+	s.clients[client] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+	}()
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-client:
+			fmt.Fprintf(w, "data: %s\n\n", event)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package devserver
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testServer(t *testing.T) *Server {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":         "<html><body><p>home</p></body></html>",
+		"posts/a/index.html": "<HTML><BODY><p>a</p></BODY></HTML>",
+		"fragment.html":      "<p>no body</p>",
+		"style.css":          "body { color: red; }",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewServer(dir)
+}
+
+func get(server *Server, path string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder
+}
+
+func TestInject(t *testing.T) {
+	server := testServer(t)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "<html><body><p>home</p>" + reloadScript + "</body></html>"},
+		{"/index.html", "<html><body><p>home</p>" + reloadScript + "</body></html>"},
+		{"/posts/a/", "<HTML><BODY><p>a</p>" + reloadScript + "</BODY></HTML>"},
+		{"/fragment.html", "<p>no body</p>" + reloadScript},
+		{"/style.css", "body { color: red; }"},
+	}
+	for _, test := range tests {
+		response := get(server, test.path)
+		if response.Code != http.StatusOK {
+			t.Errorf("GET %s: status %d, want 200", test.path, response.Code)
+		}
+		if got := response.Body.String(); got != test.want {
+			t.Errorf("GET %s =\n%s\nwant\n%s", test.path, got, test.want)
+		}
+	}
+	if got := get(server, "/posts/a").Code; got != http.StatusMovedPermanently {
+		t.Errorf("GET /posts/a: status %d, want a redirect", got)
+	}
+	if got := get(server, "/missing/").Code; got != http.StatusNotFound {
+		t.Errorf("GET /missing/: status %d, want 404", got)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	server := testServer(t)
+	server.Reload([]error{errors.New("posts/a.md:3: <bad> shortcode"), errors.New("second")})
+	page := get(server, "/").Body.String()
+	if !strings.Contains(page, `id="__ssg-overlay"`) || !strings.Contains(page, "posts/a.md:3: &lt;bad&gt; shortcode\nsecond") {
+		t.Errorf("page of a failed build has no overlay with the errors:\n%s", page)
+	}
+	if !strings.HasSuffix(page, reloadScript+"</body></html>") {
+		t.Errorf("page of a failed build has no reload script:\n%s", page)
+	}
+	// a page the failed build did not write still shows the overlay
+	if missing := get(server, "/missing/"); missing.Code != http.StatusOK || !strings.Contains(missing.Body.String(), "__ssg-overlay") {
+		t.Errorf("GET /missing/ of a failed build: status %d\n%s", missing.Code, missing.Body)
+	}
+
+	server.Reload(nil)
+	if page := get(server, "/").Body.String(); strings.Contains(page, "__ssg-overlay") {
+		t.Errorf("page of a fixed build still has the overlay:\n%s", page)
+	}
+}
+
+func TestReloadEvents(t *testing.T) {
+	server := testServer(t)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	response, err := http.Get(httpServer.URL + ReloadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if got := response.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", got)
+	}
+	reader := bufio.NewReader(response.Body)
+	// the page is listening once it gets the comment
+	if line, err := reader.ReadString('\n'); err != nil || line != ": connected\n" {
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+	reader.ReadString('\n')
+	server.Reload(nil)
+	if line, err := reader.ReadString('\n'); err != nil || line != "data: reload\n" {
+		t.Errorf("event = %q, %v, want data: reload", line, err)
+	}
+}
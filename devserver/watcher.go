@@ -0,0 +1,101 @@
+package devserver
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher polls files and directory trees for changes. Polling keeps it
+// portable and free of dependencies, and is cheap enough for a blog.
+type Watcher struct {
+	Paths    []string
+	Interval time.Duration
+	last     map[string]fileState
+}
+
+func NewWatcher(interval time.Duration, paths ...string) *Watcher {
+	w := &Watcher{Paths: paths, Interval: interval}
+	w.last = w.snapshot()
+	return w
+}
+
+func (w *Watcher) snapshot() map[string]fileState {
+	states := make(map[string]fileState)
+	for _, root := range w.Paths {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			states[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+	}
+	return states
+}
+
+// Changed returns the paths created, modified or deleted since the last
+// call, sorted.
+func (w *Watcher) Changed() []string {
+	current := w.snapshot()
+	changed := []string{}
+	for path, state := range current {
+		if previous, ok := w.last[path]; !ok || previous != state {
+			changed = append(changed, path)
+		}
+	}
+	for path := range w.last {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	w.last = current
+	sort.Strings(changed)
+	return changed
+}
+
+// Watch sends the changed paths on the returned channel every time
+// something changes. Changes arriving in quick succession, like an editor
+// writing a swap file and then the file, are sent together.
+func (w *Watcher) Watch() <-chan []string {
+	changes := make(chan []string)
+	go func() {
+		for {
+			time.Sleep(w.Interval)
+			changed := w.Changed()
+			if len(changed) == 0 {
+				continue
+			}
+			for {
+				time.Sleep(w.Interval / 2)
+				more := w.Changed()
+				if len(more) == 0 {
+					break
+				}
+				changed = append(changed, more...)
+			}
+			changes <- changed
+		}
+	}()
+	return changes
+}
+
+// Within reports whether path is dir or inside of it.
+func Within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,92 @@
+package devserver
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChanged(t *testing.T) {
+	dir := t.TempDir()
+	post := filepath.Join(dir, "posts", "a.md")
+	config := filepath.Join(t.TempDir(), "ssg.json")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(post, "first")
+	write(config, "{}")
+	watcher := NewWatcher(time.Millisecond, dir, config)
+
+	check := func(step string, want ...string) {
+		t.Helper()
+		if want == nil {
+			want = []string{}
+		}
+		if got := watcher.Changed(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Changed() = %v, want %v", step, got, want)
+		}
+	}
+	check("nothing changed")
+	write(post, "second version")
+	check("file modified", post)
+	check("nothing changed since")
+	added := filepath.Join(dir, "posts", "new", "b.md")
+	write(added, "new")
+	write(config, `{"blog": {}}`)
+	check("file added, config changed", added, config)
+	if err := os.Remove(post); err != nil {
+		t.Fatal(err)
+	}
+	check("file deleted", post)
+	// a file touched without a change of size is told by its time
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(added, later, later); err != nil {
+		t.Fatal(err)
+	}
+	check("file touched", added)
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	watcher := NewWatcher(10*time.Millisecond, dir)
+	changes := watcher.Watch()
+	post := filepath.Join(dir, "a.md")
+	if err := os.WriteFile(post, []byte("post"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case changed := <-changes:
+		if !reflect.DeepEqual(changed, []string{post}) {
+			t.Errorf("Watch() sent %v, want %v", changed, []string{post})
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch() sent no change")
+	}
+}
+
+func TestWithin(t *testing.T) {
+	tests := []struct {
+		path, dir string
+		want      bool
+	}{
+		{"public", "public", true},
+		{"public/posts/a.html", "public", true},
+		{"public-old/a.html", "public", false},
+		{"posts/a.md", "public", false},
+		{"../public/a.html", ".", false},
+		{"/site/public/a.html", "/site", true},
+	}
+	for _, test := range tests {
+		if got := Within(test.path, test.dir); got != test.want {
+			t.Errorf("Within(%q, %q) = %v, want %v", test.path, test.dir, got, test.want)
+		}
+	}
+}
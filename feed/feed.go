@@ -0,0 +1,325 @@
+// Package feed writes a list of posts as an RSS 2.0, Atom 1.0 or JSON Feed
+// 1.1 document, all from the same Feed.
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Feed is a site or a part of it with its newest items. Every URL is
+// absolute.
+type Feed struct {
+	Title       string
+	Description string
+	Language    string
+	// HomeURL is the page the feed is about, FeedURL the feed itself,
+	// which differs for every format
+	HomeURL string
+	FeedURL string
+	Authors []Author
+	Items   []Item
+}
+
+// Item is a post of a feed.
+type Item struct {
+	// ID identifies the item for good, its URL
+	ID      string
+	URL     string
+	Title   string
+	Summary string
+	// ContentHTML is the whole post, as HTML
+	ContentHTML string
+	Published   time.Time
+	Updated     time.Time
+	Categories  []string
+	Authors     []Author
+}
+
+// Author is the author of a feed or an item, Email and URL are optional.
+type Author struct {
+	Name  string
+	Email string
+	URL   string
+}
+
+// Updated returns the time the newest item was updated at, the zero time
+// for a feed without items.
+func (f Feed) Updated() time.Time {
+	updated := time.Time{}
+	for _, item := range f.Items {
+		if item.updated().After(updated) {
+			updated = item.updated()
+		}
+	}
+	return updated
+}
+
+func (i Item) updated() time.Time {
+	if i.Updated.IsZero() {
+		return i.Published
+	}
+	return i.Updated
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Content string     `xml:"xmlns:content,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Self          atomLink  `xml:"atom:link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language,omitempty"`
+	PubDate       string    `xml:"pubDate,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	Description string   `xml:"description,omitempty"`
+	PubDate     string   `xml:"pubDate"`
+	Creators    []string `xml:"dc:creator"`
+	Categories  []string `xml:"category"`
+	Content     cdata    `xml:"content:encoded"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// xmlText replaces the characters XML does not allow, which encoding/xml
+// only does for escaped text and not for CDATA sections.
+func xmlText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || r >= 0x20 && r <= 0xD7FF ||
+			r >= 0xE000 && r <= 0xFFFD || r >= 0x10000 && r <= utf8.MaxRune {
+			return r
+		}
+		return utf8.RuneError
+	}, s)
+}
+
+// RSS returns the feed as an RSS 2.0 document, with the content of the
+// items in content:encoded.
+func (f Feed) RSS() ([]byte, error) {
+	channel := rssChannel{
+		Title:       f.Title,
+		Link:        f.HomeURL,
+		Self:        atomLink{Href: f.FeedURL, Rel: "self", Type: "application/rss+xml"},
+		Description: f.Description,
+		Language:    f.Language,
+		Items:       []rssItem{},
+	}
+	if updated := f.Updated(); !updated.IsZero() {
+		channel.PubDate = updated.Format(time.RFC1123Z)
+		channel.LastBuildDate = updated.Format(time.RFC1123Z)
+	}
+	for _, item := range f.Items {
+		rssItem := rssItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			GUID:        rssGUID{IsPermaLink: item.ID == item.URL, Value: item.ID},
+			Description: item.Summary,
+			PubDate:     item.Published.Format(time.RFC1123Z),
+			Categories:  item.Categories,
+			Content:     cdata{xmlText(item.ContentHTML)},
+		}
+		for _, author := range item.Authors {
+			rssItem.Creators = append(rssItem.Creators, author.Name)
+		}
+		channel.Items = append(channel.Items, rssItem)
+	}
+	return marshalXML(rss{
+		Version: "2.0",
+		Content: "http://purl.org/rss/1.0/modules/content/",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: channel,
+	})
+}
+
+type atomFeed struct {
+	XMLName  xml.Name     `xml:"feed"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Lang     string       `xml:"xml:lang,attr,omitempty"`
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	Updated  string       `xml:"updated"`
+	Links    []atomLink   `xml:"link"`
+	Authors  []atomAuthor `xml:"author"`
+	Entries  []atomEntry  `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+	URI   string `xml:"uri,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomAuthor   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Atom returns the feed as an Atom 1.0 document.
+func (f Feed) Atom() ([]byte, error) {
+	feed := atomFeed{
+		Xmlns:    "http://www.w3.org/2005/Atom",
+		Lang:     f.Language,
+		ID:       f.HomeURL,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  f.Updated().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.FeedURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.HomeURL, Rel: "alternate", Type: "text/html"},
+		},
+		Authors: atomAuthors(f.Authors),
+		Entries: []atomEntry{},
+	}
+	for _, item := range f.Items {
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Link:      atomLink{Href: item.URL, Rel: "alternate", Type: "text/html"},
+			Published: item.Published.Format(time.RFC3339),
+			Updated:   item.updated().Format(time.RFC3339),
+			Authors:   atomAuthors(item.Authors),
+			Summary:   item.Summary,
+			Content:   atomContent{Type: "html", Value: item.ContentHTML},
+		}
+		for _, category := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return marshalXML(feed)
+}
+
+func atomAuthors(authors []Author) []atomAuthor {
+	atom := []atomAuthor{}
+	for _, author := range authors {
+		atom = append(atom, atomAuthor{Name: author.Name, Email: author.Email, URI: author.URL})
+	}
+	return atom
+}
+
+func marshalXML(v any) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+type jsonFeed struct {
+	Version     string       `json:"version"`
+	Title       string       `json:"title"`
+	HomePageURL string       `json:"home_page_url"`
+	FeedURL     string       `json:"feed_url"`
+	Description string       `json:"description,omitempty"`
+	Language    string       `json:"language,omitempty"`
+	Authors     []jsonAuthor `json:"authors,omitempty"`
+	Items       []jsonItem   `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type jsonItem struct {
+	ID            string       `json:"id"`
+	URL           string       `json:"url"`
+	Title         string       `json:"title"`
+	ContentHTML   string       `json:"content_html"`
+	Summary       string       `json:"summary,omitempty"`
+	DatePublished string       `json:"date_published"`
+	DateModified  string       `json:"date_modified,omitempty"`
+	Authors       []jsonAuthor `json:"authors,omitempty"`
+	Tags          []string     `json:"tags,omitempty"`
+}
+
+// JSON returns the feed as a JSON Feed 1.1 document.
+func (f Feed) JSON() ([]byte, error) {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.HomeURL,
+		FeedURL:     f.FeedURL,
+		Description: f.Description,
+		Language:    f.Language,
+		Authors:     jsonAuthors(f.Authors),
+		Items:       []jsonItem{},
+	}
+	for _, item := range f.Items {
+		jsonItem := jsonItem{
+			ID:            item.ID,
+			URL:           item.URL,
+			Title:         item.Title,
+			ContentHTML:   item.ContentHTML,
+			Summary:       item.Summary,
+			DatePublished: item.Published.Format(time.RFC3339),
+			Authors:       jsonAuthors(item.Authors),
+			Tags:          item.Categories,
+		}
+		if !item.Updated.IsZero() {
+			jsonItem.DateModified = item.Updated.Format(time.RFC3339)
+		}
+		feed.Items = append(feed.Items, jsonItem)
+	}
+	data, err := json.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func jsonAuthors(authors []Author) []jsonAuthor {
+	list := []jsonAuthor{}
+	for _, author := range authors {
+		list = append(list, jsonAuthor{Name: author.Name, URL: author.URL})
+	}
+	return list
+}
@@ -0,0 +1,234 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() Feed {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	return Feed{
+		Title:       "Blog",
+		Description: "Posts",
+		Language:    "en-us",
+		HomeURL:     "https://example.com/",
+		FeedURL:     "https://example.com/rss.xml",
+		Authors:     []Author{{Name: "Meet", Email: "meet@example.com", URL: "https://github.com/meet"}},
+		Items: []Item{
+			{
+				ID:          "https://example.com/b",
+				URL:         "https://example.com/b",
+				Title:       "B & more",
+				Summary:     "About <b>",
+				ContentHTML: "<p>Ends with ]]> and a \x01 control</p>",
+				Published:   day(2),
+				Updated:     day(5),
+				Categories:  []string{"go", "web"},
+				Authors:     []Author{{Name: "Guest"}},
+			},
+			{
+				ID:          "urn:post:a",
+				URL:         "https://example.com/a",
+				Title:       "A",
+				ContentHTML: "<p>A</p>",
+				Published:   day(3),
+			},
+		},
+	}
+}
+
+func TestUpdated(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []Item
+		want  time.Time
+	}{
+		{"no items", nil, time.Time{}},
+		{"newest update", testFeed().Items, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"published without updates", testFeed().Items[1:], time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		if got := (Feed{Items: test.items}).Updated(); !got.Equal(test.want) {
+			t.Errorf("%s: Updated() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRSS(t *testing.T) {
+	data, err := testFeed().RSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			// the link of the channel and the atom:link to the feed
+			Links []struct {
+				XMLName xml.Name
+				Href    string `xml:"href,attr"`
+				Rel     string `xml:"rel,attr"`
+				Value   string `xml:",chardata"`
+			} `xml:"link"`
+			Items []struct {
+				Title string `xml:"title"`
+				GUID  struct {
+					IsPermaLink bool   `xml:"isPermaLink,attr"`
+					Value       string `xml:",chardata"`
+				} `xml:"guid"`
+				PubDate    string   `xml:"pubDate"`
+				Creators   []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				Categories []string `xml:"category"`
+				Content    string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(data, &document); err != nil {
+		t.Fatalf("the RSS feed is not XML: %v\n%s", err, data)
+	}
+	channel := document.Channel
+	link, self := "", ""
+	for _, l := range channel.Links {
+		if l.XMLName.Space == "http://www.w3.org/2005/Atom" {
+			self = l.Href + " " + l.Rel
+		} else {
+			link = l.Value
+		}
+	}
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"version", document.Version, "2.0"},
+		{"title", channel.Title, "Blog"},
+		{"link", link, "https://example.com/"},
+		{"self link", self, "https://example.com/rss.xml self"},
+		{"last build date", channel.LastBuildDate, "Fri, 05 Jan 2024 00:00:00 +0000"},
+		{"items", len(channel.Items), 2},
+		{"item title", channel.Items[0].Title, "B & more"},
+		{"permalink guid", channel.Items[0].GUID.IsPermaLink, true},
+		{"other guid", channel.Items[1].GUID.IsPermaLink, false},
+		{"pubDate", channel.Items[0].PubDate, "Tue, 02 Jan 2024 00:00:00 +0000"},
+		{"creator", strings.Join(channel.Items[0].Creators, ","), "Guest"},
+		{"categories", strings.Join(channel.Items[0].Categories, ","), "go,web"},
+		{"content", channel.Items[0].Content, "<p>Ends with ]]> and a � control</p>"},
+	}
+	for _, check := range checks {
+		if check.got != check.want {
+			t.Errorf("%s = %v, want %v", check.name, check.got, check.want)
+		}
+	}
+}
+
+func TestAtom(t *testing.T) {
+	data, err := testFeed().Atom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Links   []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Authors []struct {
+			Name  string `xml:"name"`
+			Email string `xml:"email"`
+			URI   string `xml:"uri"`
+		} `xml:"author"`
+		Entries []struct {
+			ID         string `xml:"id"`
+			Published  string `xml:"published"`
+			Updated    string `xml:"updated"`
+			Categories []struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+			Content struct {
+				Type  string `xml:"type,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &document); err != nil {
+		t.Fatalf("the Atom feed is not XML: %v\n%s", err, data)
+	}
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"id", document.ID, "https://example.com/"},
+		{"updated", document.Updated, "2024-01-05T00:00:00Z"},
+		{"links", len(document.Links), 2},
+		{"self link", document.Links[0].Rel + " " + document.Links[0].Href, "self https://example.com/rss.xml"},
+		{"author", document.Authors[0].Name + " " + document.Authors[0].Email + " " + document.Authors[0].URI, "Meet meet@example.com https://github.com/meet"},
+		{"entries", len(document.Entries), 2},
+		{"entry updated", document.Entries[0].Updated, "2024-01-05T00:00:00Z"},
+		{"entry updated when published", document.Entries[1].Updated, "2024-01-03T00:00:00Z"},
+		{"categories", len(document.Entries[0].Categories), 2},
+		{"content type", document.Entries[1].Content.Type, "html"},
+		{"content", document.Entries[1].Content.Value, "<p>A</p>"},
+	}
+	for _, check := range checks {
+		if check.got != check.want {
+			t.Errorf("%s = %v, want %v", check.name, check.got, check.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := testFeed().JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("the JSON feed is not JSON: %v\n%s", err, data)
+	}
+	items := document["items"].([]interface{})
+	first, second := items[0].(map[string]interface{}), items[1].(map[string]interface{})
+	checks := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"version", document["version"], "https://jsonfeed.org/version/1.1"},
+		{"feed_url", document["feed_url"], "https://example.com/rss.xml"},
+		{"language", document["language"], "en-us"},
+		{"items", len(items), 2},
+		{"date_published", first["date_published"], "2024-01-02T00:00:00Z"},
+		{"date_modified", first["date_modified"], "2024-01-05T00:00:00Z"},
+		{"no date_modified", second["date_modified"], nil},
+		{"no summary", second["summary"], nil},
+		{"content_html", second["content_html"], "<p>A</p>"},
+	}
+	for _, check := range checks {
+		if check.got != check.want {
+			t.Errorf("%s = %v, want %v", check.name, check.got, check.want)
+		}
+	}
+}
+
+func TestEmptyFeed(t *testing.T) {
+	feed := Feed{Title: "Empty", HomeURL: "https://example.com/", FeedURL: "https://example.com/feed.json"}
+	for name, write := range map[string]func(Feed) ([]byte, error){"RSS": Feed.RSS, "Atom": Feed.Atom, "JSON": Feed.JSON} {
+		data, err := write(feed)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if name == "JSON" {
+			if !strings.Contains(string(data), `"items": []`) {
+				t.Errorf("%s feed without items has no empty items list:\n%s", name, data)
+			}
+			continue
+		}
+		if err := xml.Unmarshal(data, new(struct{})); err != nil {
+			t.Errorf("%s feed without items is not XML: %v", name, err)
+		}
+	}
+}
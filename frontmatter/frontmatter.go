@@ -0,0 +1,307 @@
+// Package frontmatter splits posts into their front matter and body, and
+// decodes the front matter.
+//
+// Three formats are understood:
+//
+//   - YAML between "---" lines. The opening line may be left out, as in
+//     the older posts of this blog, the block then ends at the first "---"
+//     line.
+//   - TOML between "+++" lines.
+//   - A JSON object at the start of the file.
+//
+// A byte order mark and CRLF line endings are tolerated.
+package frontmatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+	"gopkg.in/yaml.v3"
+)
+
+type Format int
+
+const (
+	YAML Format = iota
+	TOML
+	JSON
+)
+
+func (f Format) String() string {
+	switch f {
+	case TOML:
+		return "TOML"
+	case JSON:
+		return "JSON"
+	}
+	return "YAML"
+}
+
+// Document is a source file split in two.
+type Document struct {
+	Format Format
+	// Fenced is false for YAML front matter without the opening "---".
+	Fenced bool
+	// FrontMatter is the front matter without its fences.
+	FrontMatter []byte
+	// Body is everything after the line closing the front matter.
+	Body []byte
+}
+
+var ErrNoFrontMatter = errors.New("no front matter found")
+
+var bom = []byte("\xef\xbb\xbf")
+
+// Split separates data into front matter and body.
+func Split(data []byte) (Document, error) {
+	data = bytes.TrimPrefix(data, bom)
+	first, rest := cutLine(data)
+	switch {
+	case bytes.HasPrefix(data, []byte("{")):
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return Document{}, fmt.Errorf("JSON front matter: %w", err)
+		}
+		end := int(decoder.InputOffset())
+		_, body := cutLine(data[end:])
+		return Document{Format: JSON, Fenced: true, FrontMatter: raw, Body: body}, nil
+	case string(first) == "---":
+		return splitFenced(YAML, "---", rest)
+	case string(first) == "+++":
+		return splitFenced(TOML, "+++", rest)
+	}
+	// the older posts have YAML front matter with only the closing fence
+	document, err := splitFenced(YAML, "---", data)
+	if err != nil {
+		return Document{}, ErrNoFrontMatter
+	}
+	document.Fenced = false
+	return document, nil
+}
+
+func splitFenced(format Format, fence string, data []byte) (Document, error) {
+	for offset := 0; offset < len(data); {
+		line, rest := cutLine(data[offset:])
+		if string(line) == fence {
+			return Document{
+				Format:      format,
+				Fenced:      true,
+				FrontMatter: data[:offset],
+				Body:        rest,
+			}, nil
+		}
+		offset = len(data) - len(rest)
+	}
+	return Document{}, fmt.Errorf("%s front matter is not closed by a %q line", format, fence)
+}
+
+// cutLine returns the first line of data, without its line ending, and
+// the data after it.
+func cutLine(data []byte) ([]byte, []byte) {
+	line, rest, found := bytes.Cut(data, []byte("\n"))
+	if !found {
+		rest = nil
+	}
+	return bytes.TrimSuffix(line, []byte("\r")), rest
+}
+
+// knownKeys are the front matter keys with a field in models.FrontMatter,
+// the others end up in its Extras.
+var knownKeys = []string{"title", "description", "status", "type", "date", "slug", "tags", "image_url", "Extras"}
+
+// Decode decodes the front matter of document. Keys without a field in
+// models.FrontMatter are kept in its Extras.
+func Decode(document Document) (models.FrontMatter, error) {
+	var frontmatter models.FrontMatter
+	extras := make(map[string]interface{})
+	var err error
+	switch document.Format {
+	case JSON:
+		if err = json.Unmarshal(document.FrontMatter, &extras); err == nil {
+			err = json.Unmarshal(document.FrontMatter, &frontmatter)
+		}
+	case YAML:
+		if err = yaml.Unmarshal(document.FrontMatter, &extras); err == nil {
+			err = yaml.Unmarshal(document.FrontMatter, &frontmatter)
+		}
+	case TOML:
+		// TOML dates are not strings, so the fields are filled through
+		// JSON once the dates are formatted
+		if _, err = toml.Decode(string(document.FrontMatter), &extras); err == nil {
+			var data []byte
+			data, err = json.Marshal(formatDates(extras))
+			if err == nil {
+				err = json.Unmarshal(data, &frontmatter)
+			}
+		}
+	}
+	if err != nil {
+		return frontmatter, fmt.Errorf("%s front matter: %w", document.Format, err)
+	}
+	// JSON front matter written by this package nests the extras in an
+	// "Extras" object
+	if nested, ok := extras["Extras"].(map[string]interface{}); ok {
+		for key, value := range nested {
+			extras[key] = value
+		}
+	}
+	for _, key := range knownKeys {
+		delete(extras, key)
+	}
+	if len(extras) > 0 {
+		frontmatter.Extras = extras
+	}
+	return frontmatter, nil
+}
+
+func formatDates(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = formatDates(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = formatDates(item)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			formatDates(item)
+		}
+	case time.Time:
+		// local dates and times are decoded in zones named after them
+		switch v.Location().String() {
+		case "date-local":
+			return v.Format(time.DateOnly)
+		case "datetime-local":
+			return v.Format("2006-01-02T15:04:05")
+		case "time-local":
+			return v.Format(time.TimeOnly)
+		}
+		return v.Format(time.RFC3339)
+	}
+	return value
+}
+
+// Encode writes frontmatter in the format and layout of document, followed
+// by its body, so that decoding and encoding a document gives it back.
+func Encode(document Document, frontmatter interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	switch document.Format {
+	case JSON:
+		data, err := jsonObject(frontmatter)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(data)
+		buffer.WriteString("\n")
+	case YAML:
+		data, err := yaml.Marshal(frontmatter)
+		if err != nil {
+			return nil, err
+		}
+		if document.Fenced {
+			buffer.WriteString("---\n")
+		}
+		buffer.Write(data)
+		buffer.WriteString("---\n")
+	case TOML:
+		table, err := tomlTable(frontmatter)
+		if err != nil {
+			return nil, err
+		}
+		buffer.WriteString("+++\n")
+		if err := toml.NewEncoder(&buffer).Encode(table); err != nil {
+			return nil, err
+		}
+		buffer.WriteString("+++\n")
+	}
+	buffer.Write(document.Body)
+	return buffer.Bytes(), nil
+}
+
+// jsonObject encodes frontmatter as a JSON object keyed like its JSON
+// form, the known keys first in their order, then the Extras inlined in
+// sorted order.
+func jsonObject(frontmatter interface{}) ([]byte, error) {
+	data, err := json.Marshal(frontmatter)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	extras := make(map[string]json.RawMessage)
+	if nested, ok := fields["Extras"]; ok {
+		if err := json.Unmarshal(nested, &extras); err != nil {
+			return nil, err
+		}
+		delete(fields, "Extras")
+	}
+	keys := []string{}
+	for _, key := range knownKeys {
+		if _, ok := fields[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	others := []string{}
+	for key, value := range extras {
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+			others = append(others, key)
+		}
+	}
+	for key := range fields {
+		if !slices.Contains(keys, key) && !slices.Contains(others, key) {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, key := range append(keys, others...) {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		buffer.Write(fields[key])
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// tomlTable converts frontmatter to a table keyed like its JSON form, with
+// the Extras inlined and the empty values left out, as TOML has no null.
+func tomlTable(frontmatter interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(frontmatter)
+	if err != nil {
+		return nil, err
+	}
+	table := make(map[string]interface{})
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, err
+	}
+	if extras, ok := table["Extras"].(map[string]interface{}); ok {
+		for key, value := range extras {
+			table[key] = value
+		}
+	}
+	delete(table, "Extras")
+	for key, value := range table {
+		if value == nil {
+			delete(table, key)
+		}
+	}
+	return table, nil
+}
@@ -0,0 +1,256 @@
+package frontmatter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format Format
+		fenced bool
+		front  string
+		body   string
+		err    string
+	}{
+		{
+			name:   "YAML",
+			data:   "---\ntitle: A\n---\nBody\n",
+			format: YAML, fenced: true, front: "title: A\n", body: "Body\n",
+		},
+		{
+			name:   "YAML without the opening fence",
+			data:   "title: A\n---\nBody",
+			format: YAML, front: "title: A\n", body: "Body",
+		},
+		{
+			name:   "TOML",
+			data:   "+++\ntitle = \"A\"\n+++\nBody\n",
+			format: TOML, fenced: true, front: "title = \"A\"\n", body: "Body\n",
+		},
+		{
+			name:   "JSON",
+			data:   "{\"title\": \"A\"}\nBody\n",
+			format: JSON, fenced: true, front: `{"title": "A"}`, body: "Body\n",
+		},
+		{
+			name:   "JSON with nested objects",
+			data:   "{\"a\": {\"b\": \"}\"}}\n\nBody",
+			format: JSON, fenced: true, front: `{"a": {"b": "}"}}`, body: "\nBody",
+		},
+		{
+			name:   "byte order mark and CRLF",
+			data:   "\xef\xbb\xbf---\r\ntitle: A\r\n---\r\nBody\r\n",
+			format: YAML, fenced: true, front: "title: A\r\n", body: "Body\r\n",
+		},
+		{
+			name:   "fence with trailing text is not a fence",
+			data:   "---\ntitle: A\n--- no\n---\nBody",
+			format: YAML, fenced: true, front: "title: A\n--- no\n", body: "Body",
+		},
+		{
+			name:   "empty body",
+			data:   "---\ntitle: A\n---",
+			format: YAML, fenced: true, front: "title: A\n", body: "",
+		},
+		{
+			name: "unclosed YAML",
+			data: "---\ntitle: A\n",
+			err:  `YAML front matter is not closed by a "---" line`,
+		},
+		{
+			name: "unclosed TOML",
+			data: "+++\ntitle = \"A\"\n",
+			err:  `TOML front matter is not closed by a "+++" line`,
+		},
+		{
+			name: "invalid JSON",
+			data: "{\"title\": }\n",
+			err:  "JSON front matter: ",
+		},
+		{
+			name: "no front matter",
+			data: "Just a body\n",
+			err:  ErrNoFrontMatter.Error(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document, err := Split([]byte(test.data))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("Split() error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if document.Format != test.format || document.Fenced != test.fenced {
+				t.Errorf("Split() = %v fenced %v, want %v fenced %v", document.Format, document.Fenced, test.format, test.fenced)
+			}
+			if string(document.FrontMatter) != test.front {
+				t.Errorf("Split() front matter = %q, want %q", document.FrontMatter, test.front)
+			}
+			if string(document.Body) != test.body {
+				t.Errorf("Split() body = %q, want %q", document.Body, test.body)
+			}
+		})
+	}
+}
+
+// parse splits data and decodes its front matter, as the posts are read.
+func parse(data []byte) (models.FrontMatter, Document, error) {
+	document, err := Split(data)
+	if err != nil {
+		return models.FrontMatter{}, document, err
+	}
+	frontmatter, err := Decode(document)
+	return frontmatter, document, err
+}
+
+func TestDecode(t *testing.T) {
+	want := models.FrontMatter{
+		Title:  "A post",
+		Date:   "2024-01-31",
+		Slug:   "a-post",
+		Tags:   []string{"go", "web"},
+		Status: "published",
+		Extras: map[string]interface{}{"series": "Basics"},
+	}
+	tests := []struct {
+		name string
+		data string
+		want models.FrontMatter
+	}{
+		{
+			name: "YAML",
+			data: "---\ntitle: A post\ndate: 2024-01-31\nslug: a-post\ntags: [go, web]\nstatus: published\nseries: Basics\n---\n",
+			want: want,
+		},
+		{
+			name: "TOML",
+			data: "+++\ntitle = \"A post\"\ndate = \"2024-01-31\"\nslug = \"a-post\"\ntags = [\"go\", \"web\"]\nstatus = \"published\"\nseries = \"Basics\"\n+++\n",
+			want: want,
+		},
+		{
+			name: "TOML local date",
+			data: "+++\ntitle = \"A post\"\ndate = 2024-01-31\nslug = \"a-post\"\ntags = [\"go\", \"web\"]\nstatus = \"published\"\nseries = \"Basics\"\n+++\n",
+			want: want,
+		},
+		{
+			name: "JSON",
+			data: `{"title": "A post", "date": "2024-01-31", "slug": "a-post", "tags": ["go", "web"], "status": "published", "series": "Basics"}`,
+			want: want,
+		},
+		{
+			name: "JSON with nested extras",
+			data: `{"title": "A post", "date": "2024-01-31", "slug": "a-post", "tags": ["go", "web"], "status": "published", "Extras": {"series": "Basics"}}`,
+			want: want,
+		},
+		{
+			name: "TOML dates and times",
+			data: "+++\ndatetime = 2024-01-31T10:20:30\noffset = 2024-01-31T10:20:30Z\ntime = 10:20:30\n+++\n",
+			want: models.FrontMatter{Extras: map[string]interface{}{
+				"datetime": "2024-01-31T10:20:30",
+				"offset":   "2024-01-31T10:20:30Z",
+				"time":     "10:20:30",
+			}},
+		},
+		{
+			name: "no extras",
+			data: "---\ntitle: A post\n---\n",
+			want: models.FrontMatter{Title: "A post"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frontmatter, _, err := parse([]byte(test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(frontmatter, test.want) {
+				t.Errorf("Decode() = %#v, want %#v", frontmatter, test.want)
+			}
+		})
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	_, _, err := parse([]byte("---\ntitle: [a\n---\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "YAML front matter: ") {
+		t.Errorf("Decode() error = %v, want a YAML front matter error", err)
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"YAML", "---\ntitle: A post\ntags: [go]\nseries: Basics\n---\nBody\n"},
+		{"YAML without the opening fence", "title: A post\ntags: [go]\n---\nBody\n"},
+		{"TOML", "+++\ntitle = \"A post\"\ndate = 2024-01-31\nseries = \"Basics\"\n+++\nBody\n"},
+		{"JSON", "{\"title\": \"A post\", \"series\": \"Basics\"}\nBody\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frontmatter, document, err := parse([]byte(test.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			encoded, err := Encode(document, frontmatter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, encodedDocument, err := parse(encoded)
+			if err != nil {
+				t.Fatalf("parsing the encoded front matter: %v\n%s", err, encoded)
+			}
+			if !reflect.DeepEqual(again, frontmatter) {
+				t.Errorf("encoded front matter = %#v, want %#v\n%s", again, frontmatter, encoded)
+			}
+			if encodedDocument.Format != document.Format || encodedDocument.Fenced != document.Fenced {
+				t.Errorf("encoded as %v fenced %v, want %v fenced %v", encodedDocument.Format, encodedDocument.Fenced, document.Format, document.Fenced)
+			}
+			if string(encodedDocument.Body) != string(document.Body) {
+				t.Errorf("encoded body = %q, want %q", encodedDocument.Body, document.Body)
+			}
+		})
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		frontmatter models.FrontMatter
+		want        string
+	}{
+		{
+			name:        "no extras",
+			frontmatter: models.FrontMatter{Title: "A", Tags: []string{}},
+			want:        `{"title":"A","description":"","status":"","type":"","date":"","slug":"","tags":[],"image_url":""}`,
+		},
+		{
+			name:        "extras inlined after the fields",
+			frontmatter: models.FrontMatter{Title: "A", Extras: map[string]interface{}{"series": "Basics", "draft": true, "title": "B"}},
+			want:        `{"title":"A","description":"","status":"","type":"","date":"","slug":"","tags":null,"image_url":"","draft":true,"series":"Basics"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encoded, err := Encode(Document{Format: JSON, Fenced: true, Body: []byte("Body\n")}, test.frontmatter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := test.want + "\nBody\n"; string(encoded) != want {
+				t.Errorf("Encode() = %s, want %s", encoded, want)
+			}
+		})
+	}
+}
@@ -3,11 +3,15 @@ module github.com/mr-destructive/mr-destructive.github.io
 go 1.23.3
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/JohannesKaufmann/html-to-markdown/v2 v2.3.1
+	github.com/alecthomas/chroma/v2 v2.14.0
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/google/go-github v17.0.0+incompatible
+	github.com/mattn/go-sqlite3 v1.14.32
 	github.com/tursodatabase/libsql-client-go v0.0.0-20240902231107-85af5b9d094d
 	github.com/yuin/goldmark v1.7.8
+	github.com/yuin/goldmark-highlighting/v2 v2.0.0-20230729083705-37449abec8cc
 	golang.org/x/crypto v0.33.0
 	golang.org/x/oauth2 v0.25.0
 	gopkg.in/yaml.v3 v3.0.1
@@ -17,9 +21,9 @@ require (
 	github.com/JohannesKaufmann/dom v0.2.0 // indirect
 	github.com/antlr4-go/antlr/v4 v4.13.0 // indirect
 	github.com/coder/websocket v1.8.12 // indirect
+	github.com/dlclark/regexp2 v1.11.0 // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
-	github.com/mattn/go-sqlite3 v1.14.32 // indirect
 	golang.org/x/exp v0.0.0-20240325151524-a685a6edb6d8 // indirect
 	golang.org/x/net v0.35.0 // indirect
 )
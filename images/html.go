@@ -0,0 +1,152 @@
+package images
+
+import (
+	"html"
+	"strings"
+)
+
+// Tag is an <img> tag of an HTML document, with its attributes in order.
+type Tag struct {
+	attrs []attr
+	// end is how the tag closes, > or />
+	end string
+}
+
+type attr struct {
+	name string
+	// raw is the value as written in the document, still escaped
+	raw      string
+	hasValue bool
+}
+
+// Get returns the unescaped value of the attribute name.
+func (t *Tag) Get(name string) (string, bool) {
+	for _, a := range t.attrs {
+		if a.name == name {
+			return html.UnescapeString(a.raw), true
+		}
+	}
+	return "", false
+}
+
+// Has reports whether the tag has the attribute name.
+func (t *Tag) Has(name string) bool {
+	_, ok := t.Get(name)
+	return ok
+}
+
+// Set sets the attribute name to value, adding it after the others when the
+// tag does not have it.
+func (t *Tag) Set(name, value string) {
+	a := attr{name: name, raw: html.EscapeString(value), hasValue: true}
+	for i := range t.attrs {
+		if t.attrs[i].name == name {
+			t.attrs[i] = a
+			return
+		}
+	}
+	t.attrs = append(t.attrs, a)
+}
+
+func (t *Tag) String() string {
+	var b strings.Builder
+	b.WriteString("<img")
+	for _, a := range t.attrs {
+		b.WriteString(" " + a.name)
+		if a.hasValue {
+			b.WriteString(`="` + strings.ReplaceAll(a.raw, `"`, "&#34;") + `"`)
+		}
+	}
+	b.WriteString(t.end)
+	return b.String()
+}
+
+// RewriteTags calls rewrite with every <img> tag of the HTML document src,
+// and writes the tag back as rewrite left it when it returns true. The
+// other tags and the rest of the document are kept as they are.
+func RewriteTags(src string, rewrite func(tag *Tag) bool) string {
+	var out strings.Builder
+	lower := strings.ToLower(src)
+	for {
+		i := strings.Index(lower, "<img")
+		if i < 0 {
+			out.WriteString(src)
+			return out.String()
+		}
+		// another element, like <imgur-embed>
+		if i+4 < len(lower) && !isSpace(lower[i+4]) && lower[i+4] != '/' && lower[i+4] != '>' {
+			out.WriteString(src[:i+4])
+			src, lower = src[i+4:], lower[i+4:]
+			continue
+		}
+		out.WriteString(src[:i])
+		src, lower = src[i:], lower[i:]
+		tag, n, ok := parseTag(src)
+		if ok && rewrite(tag) {
+			out.WriteString(tag.String())
+		} else {
+			out.WriteString(src[:n])
+		}
+		src, lower = src[n:], lower[n:]
+	}
+}
+
+// parseTag parses the <img> tag s starts with, returning its length. It
+// reports false for a tag with no closing >.
+func parseTag(s string) (*Tag, int, bool) {
+	tag := &Tag{}
+	i := len("<img")
+	for i < len(s) {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		switch {
+		case i == len(s):
+			return nil, len(s), false
+		case s[i] == '>':
+			tag.end = ">"
+			return tag, i + 1, true
+		case strings.HasPrefix(s[i:], "/>"):
+			tag.end = " />"
+			return tag, i + 2, true
+		case s[i] == '/':
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && !strings.HasPrefix(s[i:], "/>") {
+			i++
+		}
+		a := attr{name: strings.ToLower(s[start:i])}
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			a.hasValue = true
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				end := strings.IndexByte(s[i+1:], s[i])
+				if end < 0 {
+					return nil, len(s), false
+				}
+				a.raw = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				a.raw = s[start:i]
+			}
+		}
+		tag.attrs = append(tag.attrs, a)
+	}
+	return nil, len(s), false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,163 @@
+// Package images makes smaller variants of the local images of a site with
+// the image packages of the standard library, so that browsers can pick
+// the one fitting the layout, and rewrites the <img> tags linking to them.
+package images
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Info describes an image file.
+type Info struct {
+	Width  int
+	Height int
+	// Format is the name of the decoder, like jpeg, png or gif
+	Format string
+	// Hash is the hex encoded sha256 of the file
+	Hash string
+}
+
+// Inspect reads the dimensions and the format of the image data without
+// decoding its pixels.
+func Inspect(data []byte) (Info, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Info{}, err
+	}
+	sum := sha256.Sum256(data)
+	return Info{
+		Width:  config.Width,
+		Height: config.Height,
+		Format: format,
+		Hash:   hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// Supported reports whether name has the extension of an image format
+// Inspect can read.
+func Supported(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		return true
+	}
+	return false
+}
+
+// Widths returns the widths variants of the image are made at, the ones
+// narrower than the image, sorted and without duplicates. Only JPEG and
+// PNG images get variants, Encode writes no other format.
+func (info Info) Widths(widths []int) []int {
+	if info.Format != "jpeg" && info.Format != "png" {
+		return nil
+	}
+	variants := []int{}
+	for _, width := range widths {
+		if width > 0 && width < info.Width {
+			variants = append(variants, width)
+		}
+	}
+	slices.Sort(variants)
+	return slices.Compact(variants)
+}
+
+// HeightAt returns the height of the variant of the image at width, keeping
+// its aspect ratio.
+func (info Info) HeightAt(width int) int {
+	return max(1, int(math.Round(float64(info.Height)*float64(width)/float64(info.Width))))
+}
+
+// VariantName returns the name of the variant of the image name at width,
+// with the first 8 characters of the hash of the image, like
+// photo.3f2a1c0b-480w.jpg.
+func VariantName(name, hash string, width int) string {
+	ext := path.Ext(name)
+	return fmt.Sprintf("%s.%s-%dw%s", strings.TrimSuffix(name, ext), hash[:min(len(hash), 8)], width, ext)
+}
+
+// Scale returns src resized to width, keeping its aspect ratio. Every pixel
+// is the average of the pixels of src it covers, weighted by how much of
+// them it covers, which keeps the details of downscaled images smooth.
+func Scale(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	info := Info{Width: bounds.Dx(), Height: bounds.Dy()}
+	height := info.HeightAt(width)
+	// premultiplied pixels average without dark fringes around transparency
+	rgba := image.NewRGBA(image.Rect(0, 0, info.Width, info.Height))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	xs := spans(info.Width, width)
+	ys := spans(info.Height, height)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	var sum [4]float64
+	for y, ySpan := range ys {
+		for x, xSpan := range xs {
+			sum = [4]float64{}
+			total := 0.0
+			for _, sy := range ySpan {
+				for _, sx := range xSpan {
+					weight := sy.weight * sx.weight
+					pixel := rgba.Pix[rgba.PixOffset(sx.index, sy.index):]
+					for c := range sum {
+						sum[c] += weight * float64(pixel[c])
+					}
+					total += weight
+				}
+			}
+			pixel := dst.Pix[dst.PixOffset(x, y):]
+			for c := range sum {
+				pixel[c] = uint8(math.Round(sum[c] / total))
+			}
+		}
+	}
+	return dst
+}
+
+type sample struct {
+	index  int
+	weight float64
+}
+
+// spans returns, for every pixel of a row of size pixels scaled from one
+// of from pixels, the source pixels it covers with the covered fraction.
+func spans(from, size int) [][]sample {
+	scale := float64(from) / float64(size)
+	spans := make([][]sample, size)
+	for i := range spans {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < from && float64(j) < end; j++ {
+			weight := math.Min(end, float64(j+1)) - math.Max(start, float64(j))
+			if weight > 0 {
+				spans[i] = append(spans[i], sample{index: j, weight: weight})
+			}
+		}
+	}
+	return spans
+}
+
+// Encode encodes img in format, jpeg with quality or png.
+func Encode(img image.Image, format string, quality int) ([]byte, error) {
+	var buffer bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: quality})
+	case "png":
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(&buffer, img)
+	default:
+		err = fmt.Errorf("cannot encode %s images", format)
+	}
+	return buffer.Bytes(), err
+}
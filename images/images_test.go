@@ -0,0 +1,159 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testImage returns an image of width by height encoded in format.
+func testImage(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := range width {
+		img.Set(x, 0, color.RGBA{R: uint8(x), A: 255})
+	}
+	var buffer bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buffer, img)
+	case "jpeg":
+		err = jpeg.Encode(&buffer, img, nil)
+	case "gif":
+		err = gif.Encode(&buffer, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestInspect(t *testing.T) {
+	for _, format := range []string{"png", "jpeg", "gif"} {
+		t.Run(format, func(t *testing.T) {
+			data := testImage(t, format, 30, 20)
+			info, err := Inspect(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Width != 30 || info.Height != 20 || info.Format != format || len(info.Hash) != 64 {
+				t.Errorf("Inspect() = %+v, want 30x20 %s with a hash", info, format)
+			}
+		})
+	}
+	if _, err := Inspect([]byte("not an image")); err == nil {
+		t.Error("Inspect() of text succeeded")
+	}
+}
+
+func TestWidths(t *testing.T) {
+	tests := []struct {
+		name   string
+		info   Info
+		widths []int
+		want   []int
+	}{
+		{"narrower widths only", Info{Width: 1000, Format: "jpeg"}, []int{480, 800, 1200}, []int{480, 800}},
+		{"no upscaling", Info{Width: 400, Format: "png"}, []int{480, 800}, []int{}},
+		{"not the width of the image", Info{Width: 800, Format: "png"}, []int{800}, []int{}},
+		{"sorted without duplicates", Info{Width: 2000, Format: "png"}, []int{1200, 480, 0, -1, 480}, []int{480, 1200}},
+		{"no variants of gifs", Info{Width: 2000, Format: "gif"}, []int{480}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.info.Widths(test.widths)
+			if !slices.Equal(got, test.want) || (got == nil) != (test.want == nil) {
+				t.Errorf("Widths(%v) = %v, want %v", test.widths, got, test.want)
+			}
+		})
+	}
+}
+
+func TestHeightAt(t *testing.T) {
+	info := Info{Width: 1200, Height: 800}
+	for width, want := range map[int]int{600: 400, 480: 320, 1: 1} {
+		if got := info.HeightAt(width); got != want {
+			t.Errorf("HeightAt(%d) = %d, want %d", width, got, want)
+		}
+	}
+}
+
+func TestVariantName(t *testing.T) {
+	tests := []struct {
+		name, hash string
+		width      int
+		want       string
+	}{
+		{"photo.jpg", "3f2a1c0b9d8e", 480, "photo.3f2a1c0b-480w.jpg"},
+		{"img/a.b.png", "3f2a1c0b9d8e", 800, "img/a.b.3f2a1c0b-800w.png"},
+		{"photo.jpg", "abc", 480, "photo.abc-480w.jpg"},
+	}
+	for _, test := range tests {
+		if got := VariantName(test.name, test.hash, test.width); got != test.want {
+			t.Errorf("VariantName(%q, %q, %d) = %q, want %q", test.name, test.hash, test.width, got, test.want)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	img, _, err := image.Decode(bytes.NewReader(testImage(t, "png", 30, 20)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scaled := Scale(img, 15)
+	if bounds := scaled.Bounds(); bounds.Dx() != 15 || bounds.Dy() != 10 {
+		t.Errorf("Scale() = %v, want 15x10", bounds)
+	}
+	data, err := Encode(scaled, "png", 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := Inspect(data); err != nil || info.Width != 15 {
+		t.Errorf("Encode() = %+v, %v, want a 15 pixels wide png", info, err)
+	}
+	if _, err := Encode(scaled, "gif", 80); err == nil {
+		t.Error("Encode() of a gif succeeded")
+	}
+}
+
+func TestRewriteTags(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"attributes added", `<p><img src="a.png" alt="A"></p>`, `<p><img src="a.png" alt="A" loading="lazy"></p>`},
+		{"unquoted value ending with a slash", `<img src=a.png/>`, `<img src="a.png/" loading="lazy">`},
+		{"self-closing with space", `<img src="a.png" />`, `<img src="a.png" loading="lazy" />`},
+		{"existing attributes kept", `<IMG SRC='a.png' Loading="eager" data-x>`, `<img src="a.png" loading="eager" data-x>`},
+		{"escaped values", `<img src="a.png" alt="&quot;A&quot; &amp; B">`, `<img src="a.png" alt="&quot;A&quot; &amp; B" loading="lazy">`},
+		{"not rewritten", `<img src="skip.png">`, `<img src="skip.png">`},
+		{"other elements", `<imgur-embed src="a.png"></imgur-embed>`, `<imgur-embed src="a.png"></imgur-embed>`},
+		{"unclosed tag", `text <img src="a.png"`, `text <img src="a.png"`},
+		{"several", `<img src="a.png"><img src="b.png">`, `<img src="a.png" loading="lazy"><img src="b.png" loading="lazy">`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := RewriteTags(test.src, func(tag *Tag) bool {
+				src, _ := tag.Get("src")
+				if strings.HasPrefix(src, "skip") {
+					return false
+				}
+				if !tag.Has("loading") {
+					tag.Set("loading", "lazy")
+				}
+				return true
+			})
+			if got != test.want {
+				t.Errorf("RewriteTags(%q) = %q, want %q", test.src, got, test.want)
+			}
+		})
+	}
+}
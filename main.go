@@ -3,35 +3,52 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"reflect"
-	"regexp"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/mr-destructive/mr-destructive.github.io/assets"
+	"github.com/mr-destructive/mr-destructive.github.io/cache"
+	"github.com/mr-destructive/mr-destructive.github.io/devserver"
+	"github.com/mr-destructive/mr-destructive.github.io/frontmatter"
+	"github.com/mr-destructive/mr-destructive.github.io/markdown"
+	"github.com/mr-destructive/mr-destructive.github.io/minify"
 	models "github.com/mr-destructive/mr-destructive.github.io/models"
+	"github.com/mr-destructive/mr-destructive.github.io/oembed"
 	"github.com/mr-destructive/mr-destructive.github.io/plugins"
-	"gopkg.in/yaml.v3"
-
-	"github.com/yuin/goldmark"
+	"github.com/mr-destructive/mr-destructive.github.io/shortcode"
 )
 
+// WalkAndListFiles lists the posts below dirPath: its markdown files, and
+// the index.md of every page bundle, a directory below dirPath holding a
+// post along with its images and attachments, which are left out.
 func WalkAndListFiles(dirPath string) ([]string, error) {
 	var files []string
 	err := filepath.WalkDir(dirPath, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if !d.IsDir() {
+		if d.IsDir() {
+			index := filepath.Join(path, plugins.BundleIndex)
+			if _, err := os.Stat(index); err == nil && path != filepath.Clean(dirPath) {
+				files = append(files, index)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) == ".md" {
 			files = append(files, path)
 		}
 		return nil
@@ -54,8 +71,25 @@ func ReadFiles(files []string) ([][]byte, error) {
 	return filesBytes, nil
 }
 
-func ReadPosts(files []string) ([]models.Post, error) {
+// NewRenderer returns the markdown renderer of the posts, expanding the
+// built-in shortcodes and those of the shortcodes dir of the templates,
+// with embeds fetching the HTML of embedded links.
+func NewRenderer(config models.SSG_CONFIG, embeds *oembed.Client) (*markdown.Renderer, error) {
+	shortcodes, err := shortcode.New(filepath.Join(config.Blog.TemplatesDir, "shortcodes"))
+	if err != nil {
+		return nil, fmt.Errorf("loading the shortcodes: %w", err)
+	}
+	shortcodes.Embeds = embeds
+	return markdown.New(config.Markdown).WithShortcodes(shortcodes), nil
+}
+
+// ReadPosts parses files into posts, converting their markdown with
+// renderer. Files that cannot be parsed are left out and reported in the
+// returned error, next to the posts that could. Posts with stand-ins for
+// some embeds are kept but not cached, so a later build fetches them again.
+func ReadPosts(files []string, renderer *markdown.Renderer, buildCache *cache.BuildCache) ([]models.Post, error) {
 	var posts []models.Post
+	var errs []error
 
 	// Read file contents
 	filesBytes, err := ReadFiles(files)
@@ -64,89 +98,67 @@ func ReadPosts(files []string) ([]models.Post, error) {
 	}
 
 	// Iterate through files
-	for _, fileBytes := range filesBytes {
-		var success bool
-		var frontmatterObj models.FrontMatter
-		var contentBytes []byte
-		var requiredFields []string = []string{"title", "description", "status", "type", "date", "slug", "tags", "image_url"}
-
-		// Attempt to detect JSON front matter
-		jsonSeparator := []byte("}\n\n")
-		jsonIndex := strings.Index(string(fileBytes), string(jsonSeparator))
-
-		if jsonIndex != -1 {
-			frontmatterBytes := fileBytes[:jsonIndex+1] // Keep closing brace
-			contentBytes = fileBytes[jsonIndex+2:]      // Skip the separator
-
-			// Unmarshal into a temporary map to capture extra fields
-			tempMap := make(map[string]interface{})
-			if err := json.Unmarshal(frontmatterBytes, &tempMap); err == nil {
-				success = true
-
-				// Extract known fields into the struct
-				if err := json.Unmarshal(frontmatterBytes, &frontmatterObj); err != nil {
-					log.Printf("Error parsing JSON front matter: %v", err)
-					continue
-				}
-
-				// Remove known keys and store the rest in Extras
-				for _, key := range requiredFields {
-					delete(tempMap, key)
-				}
-				frontmatterObj.Extras = tempMap
-			}
+	for i, fileBytes := range filesBytes {
+		document, err := frontmatter.Split(fileBytes)
+		if err != nil {
+			errs = append(errs, plugins.PageError(files[i], "", err))
+			continue
 		}
-
-		// Attempt to detect YAML front matter
-		if !success {
-			yamlSeparator := []byte("---\n\n")
-			yamlIndex := strings.Index(string(fileBytes), string(yamlSeparator))
-
-			if yamlIndex != -1 {
-				frontmatterBytes := fileBytes[:yamlIndex]
-				contentBytes = fileBytes[yamlIndex+len(yamlSeparator):]
-
-				// Unmarshal into a temporary map to capture extra fields
-				tempMap := make(map[string]interface{})
-				if err := yaml.Unmarshal(frontmatterBytes, &tempMap); err == nil {
-
-					// Extract known fields into the struct
-					if err := yaml.Unmarshal(frontmatterBytes, &frontmatterObj); err != nil {
-						log.Printf("Error parsing YAML front matter: %v", err)
-						continue
-					}
-
-					// Remove known keys and store the rest in Extras
-					for _, key := range requiredFields {
-						delete(tempMap, key)
-					}
-					frontmatterObj.Extras = tempMap
-				} else {
-					log.Printf("Error parsing YAML front matter: %v", err)
-					continue
-				}
-			} else {
-				log.Printf("No valid front matter found in file", frontmatterObj)
-				log.Println(string(fileBytes))
+		frontmatterObj, err := frontmatter.Decode(document)
+		if err != nil {
+			errs = append(errs, plugins.PageError(files[i], "", err))
+			continue
+		}
+		contentBytes := document.Body
+		bodyLine := bytes.Count(fileBytes[:len(fileBytes)-len(contentBytes)], []byte("\n")) + 1
+
+		// Convert Markdown to HTML, unless the same source was converted before
+		sourceHash := cache.Hash(string(fileBytes))
+		htmlHash := cache.Hash(sourceHash, renderer.Key)
+		contentHTML, ok := buildCache.HTML(htmlHash)
+		if !ok {
+			var contentBuffer bytes.Buffer
+			err := renderer.Render(files[i], bodyLine, contentBytes, &contentBuffer)
+			incomplete := errors.Is(err, shortcode.ErrIncomplete)
+			if incomplete {
+				log.Println(err)
+			} else if err != nil {
+				errs = append(errs, markdownError(files[i], err))
 				continue
 			}
-		}
-		// Convert Markdown to HTML
-		var contentBuffer bytes.Buffer
-		if err := goldmark.Convert(contentBytes, &contentBuffer); err != nil {
-			log.Printf("Error processing Markdown: %v", err)
-			continue
+			contentHTML = contentBuffer.Bytes()
+			if !incomplete {
+				if err := buildCache.StoreHTML(htmlHash, contentHTML); err != nil {
+					log.Printf("Error caching Markdown: %v", err)
+				}
+			}
 		}
 
 		// Append post
 		posts = append(posts, models.Post{
 			Frontmatter: frontmatterObj,
-			Content:     template.HTML(contentBuffer.String()),
+			Content:     template.HTML(contentHTML),
 			Markdown:    string(contentBytes),
+			SourcePath:  files[i],
+			SourceHash:  sourceHash,
 		})
 	}
 
-	return posts, nil
+	return posts, errors.Join(errs...)
+}
+
+// markdownError is the diagnostic of a post whose markdown could not be
+// rendered, located at the line of the shortcode at fault if it is one.
+func markdownError(file string, err error) error {
+	var shortcodeErr *shortcode.Error
+	if errors.As(err, &shortcodeErr) {
+		err = shortcodeErr.Err
+		if shortcodeErr.Name != "" {
+			err = fmt.Errorf("shortcode %q: %w", shortcodeErr.Name, err)
+		}
+		return &plugins.Diagnostic{Source: file, Line: shortcodeErr.Line, Err: err}
+	}
+	return plugins.PageError(file, "", fmt.Errorf("processing Markdown: %w", err))
 }
 
 func ReadTemplates(files []string) ([]string, error) {
@@ -161,80 +173,8 @@ func ReadTemplates(files []string) ([]string, error) {
 	return templateStrs, nil
 }
 
-func Copy(src string, dst string) error {
-	srcFiles, err := WalkAndListFiles(src)
-	if err != nil {
-		return err
-	}
-	for _, srcFileName := range srcFiles {
-		srcFile, err := os.Open(srcFileName)
-		if err != nil {
-			return err
-		}
-		defer srcFile.Close()
-
-		srcFileName = filepath.Base(srcFileName)
-		dstPath := filepath.Join(dst, srcFileName)
-		dstFile, err := os.Create(dstPath)
-		if err != nil {
-			return err
-		}
-		defer dstFile.Close()
-
-		_, err = io.Copy(dstFile, srcFile)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
-	return nil
-}
-
-func CopyCustom(src string, dst string) error {
-	srcFiles, err := WalkAndListFiles(src)
-	if err != nil {
-		return err
-	}
-	fmt.Println("srcFiles:", len(srcFiles))
-
-	for _, srcFileName := range srcFiles {
-		relPath, err := filepath.Rel(src, srcFileName)
-		if err != nil {
-			return err
-		}
-
-		// Skip copying anything inside "admin"
-		if strings.HasPrefix(relPath, "admin") {
-			continue
-		}
-
-		srcFile, err := os.Open(srcFileName)
-		if err != nil {
-			return err
-		}
-		defer srcFile.Close()
-
-		dstPath := filepath.Join(dst, relPath)
-		err = os.MkdirAll(filepath.Dir(dstPath), os.ModePerm)
-		if err != nil {
-			return err
-		}
-
-		dstFile, err := os.Create(dstPath)
-		if err != nil {
-			return err
-		}
-		defer dstFile.Close()
-
-		_, err = io.Copy(dstFile, srcFile)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
-
-	return nil
-}
-
-func GeneratePages(config models.SSG_CONFIG) error {
+func GeneratePages(ssg *models.SSG) error {
+	config := ssg.Config
 	src := config.Blog.StaticDir
 	templateFS := os.DirFS(config.Blog.TemplatesDir)
 	mdFiles := []string{}
@@ -256,14 +196,11 @@ func GeneratePages(config models.SSG_CONFIG) error {
 	}
 	for _, mdFile := range mdFiles {
 		mdFileName := filepath.Base(mdFile)
-		content, err := ReadPosts([]string{string(mdFile)})
+		content, err := ReadPosts([]string{string(mdFile)}, ssg.Markdown, nil)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
 		postContent := string(content[0].Content)
-		if err != nil {
-			log.Fatal(err)
-		}
 
 		mdFileName = strings.TrimSuffix(mdFileName, filepath.Ext(mdFileName))
 		feed := models.Feed{
@@ -288,47 +225,130 @@ func GeneratePages(config models.SSG_CONFIG) error {
 			},
 			FeedInfo: feed,
 			Config: models.SSG_CONFIG{
-				Blog: config.Blog,
+				Blog:     config.Blog,
+				Markdown: config.Markdown,
 			},
 		}
+		outputPagePath := filepath.Join(config.Blog.OutputDir, mdFileName, "index.html")
 		buffer := bytes.Buffer{}
-		t, err := template.ParseFS(templateFS, "default_page_template.html")
-		err = t.ExecuteTemplate(&buffer, "default_page_template.html", context)
+		t, err := template.New("default_page_template.html").Funcs(plugins.TemplateFuncs).Funcs(plugins.AssetFuncs(ssg.Assets, config.Blog)).ParseFS(templateFS, "default_page_template.html")
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		//create a folder with mdFileName
-		err = os.MkdirAll(filepath.Join(".", config.Blog.OutputDir, mdFileName), os.ModePerm)
+		err = t.ExecuteTemplate(&buffer, "default_page_template.html", context)
 		if err != nil {
-			log.Fatal(err)
+			return plugins.PageError(mdFile, outputPagePath, err)
 		}
-		err = os.WriteFile(filepath.Join(".", config.Blog.OutputDir, mdFileName, "index.html"), buffer.Bytes(), 0660)
+		//create a folder with mdFileName
+		err = plugins.WritePage(ssg, outputPagePath, buffer.Bytes())
 		if err != nil {
-			log.Fatal(err)
+			return plugins.PageError(mdFile, outputPagePath, err)
 		}
+		ssg.Manifest.Add(models.ManifestPage{Output: outputPagePath})
 	}
 	return nil
 }
 
 type PluginManager struct {
 	Plugins []plugins.Plugin
+	// KeepGoing executes the remaining plugins after one has failed, so
+	// that a single build reports every problem
+	KeepGoing   bool
+	Diagnostics []*plugins.Diagnostic
+	failed      map[string]bool
 }
 
 func (p *PluginManager) Register(plugin plugins.Plugin) {
 	p.Plugins = append(p.Plugins, plugin)
 }
 
-func (p *PluginManager) ExecuteAll(ssg *models.SSG) {
+// ExecuteAll executes the plugins in dependency order, one phase after the
+// other, running the hooks of every plugin around each phase. Unless
+// KeepGoing is set it stops at the first plugin that fails. Everything that
+// went wrong is collected in Diagnostics.
+func (p *PluginManager) ExecuteAll(ssg *models.SSG) error {
+	sorted, err := plugins.Sort(p.Plugins)
+	if err != nil {
+		p.Diagnostics = append(p.Diagnostics, &plugins.Diagnostic{Err: err})
+		return err
+	}
 	fmt.Println("Running plugins")
-	for _, plugin := range p.Plugins {
+	p.failed = make(map[string]bool)
+	_ = p.hook(sorted, plugins.HookBeforeRead, ssg) &&
+		p.execute(sorted, plugins.PhaseRead, ssg) &&
+		p.hook(sorted, plugins.HookAfterRead, ssg) &&
+		p.hook(sorted, plugins.HookBeforeRender, ssg) &&
+		p.execute(sorted, plugins.PhaseRender, ssg) &&
+		p.hook(sorted, plugins.HookAfterRender, ssg) &&
+		p.execute(sorted, plugins.PhaseWrite, ssg) &&
+		p.hook(sorted, plugins.HookAfterWrite, ssg)
+	if len(p.Diagnostics) > 0 {
+		return fmt.Errorf("build failed with %d errors", len(p.Diagnostics))
+	}
+	return nil
+}
+
+// hook runs hook across all plugins, and reports whether to carry on.
+func (p *PluginManager) hook(sorted []plugins.Plugin, hook plugins.Hook, ssg *models.SSG) bool {
+	diagnostics := plugins.RunHook(sorted, hook, ssg)
+	p.Diagnostics = append(p.Diagnostics, diagnostics...)
+	return p.KeepGoing || len(diagnostics) == 0
+}
+
+// execute executes the plugins of phase, and reports whether to carry on.
+// A plugin that only failed on some pages still counts as done, any other
+// failure skips the plugins that require it.
+func (p *PluginManager) execute(sorted []plugins.Plugin, phase plugins.Phase, ssg *models.SSG) bool {
+	for _, plugin := range sorted {
+		if plugins.PhaseOf(plugin) != phase {
+			continue
+		}
+		if dep := p.failedDependency(plugin); dep != "" {
+			p.failed[plugin.Name()] = true
+			p.Diagnostics = append(p.Diagnostics, &plugins.Diagnostic{
+				Plugin: plugin.Name(),
+				Err:    fmt.Errorf("skipped, it requires %s which failed", dep),
+			})
+			continue
+		}
 		fmt.Println("Running plugin:", plugin.Name())
-		plugin.Execute(ssg)
+		diagnostics := plugins.Diagnostics(plugin.Name(), plugin.Execute(ssg))
+		if len(diagnostics) == 0 {
+			continue
+		}
+		p.Diagnostics = append(p.Diagnostics, diagnostics...)
+		if !plugins.PageErrorsOnly(diagnostics) {
+			p.failed[plugin.Name()] = true
+		}
+		if !p.KeepGoing {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *PluginManager) failedDependency(plugin plugins.Plugin) string {
+	if dependent, ok := plugin.(plugins.Dependent); ok {
+		for _, dep := range dependent.Requires() {
+			if p.failed[dep] {
+				return dep
+			}
+		}
+	}
+	return ""
+}
+
+// PrintDiagnostics prints a summary of everything that went wrong.
+func PrintDiagnostics(diagnostics []*plugins.Diagnostic) {
+	fmt.Fprintf(os.Stderr, "\nBuild failed with %d errors:\n", len(diagnostics))
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintln(os.Stderr, "  "+diagnostic.Error())
 	}
 }
 
 // Config Plugin
 type ConfigPlugin struct {
-	PluginName string
+	PluginName string `json:"-"`
 }
 
 func (c *ConfigPlugin) Name() string {
@@ -336,24 +356,41 @@ func (c *ConfigPlugin) Name() string {
 }
 
 type PostReaderPlugin struct {
-	PluginName string
+	PluginName string `json:"-"`
 }
 
 func (c *PostReaderPlugin) Name() string {
 	return c.PluginName
 }
 
-func (c *PostReaderPlugin) Execute(ssg *models.SSG) {
+func (c *PostReaderPlugin) Phase() plugins.Phase {
+	return plugins.PhaseRead
+}
+
+func (c *PostReaderPlugin) Execute(ssg *models.SSG) error {
 	config := &ssg.Config
 	postFolder := config.Blog.PostsDir
 	var postFiles []string
 	postFiles, err := WalkAndListFiles(postFolder)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	postsList, err := ReadPosts(postFiles)
-	if err != nil {
-		log.Fatal(err)
+	// posts that failed to parse are reported once the others are loaded
+	postsList, readErr := ReadPosts(postFiles, ssg.Markdown, ssg.Cache)
+	errs := []error{readErr}
+	for i := range postsList {
+		post := &postsList[i]
+		if filepath.Base(post.SourcePath) != plugins.BundleIndex || filepath.Dir(post.SourcePath) == filepath.Clean(postFolder) {
+			continue
+		}
+		post.Bundle = filepath.Dir(post.SourcePath)
+		// a bundle is named after its directory
+		if post.Frontmatter.Slug == "" {
+			post.Frontmatter.Slug = filepath.Base(post.Bundle)
+		}
+		if err := plugins.ResolveBundleLinks(ssg, post); err != nil {
+			errs = append(errs, err)
+		}
 	}
 	for _, post := range postsList {
 		if post.Frontmatter.Status == "draft" {
@@ -363,16 +400,21 @@ func (c *PostReaderPlugin) Execute(ssg *models.SSG) {
 	}
 	ssg.Posts = postsList
 	fmt.Println("Posts:", len(ssg.Posts))
+	return errors.Join(errs...)
 }
 
 type RenderTemplatesPlugin struct {
-	PluginName string
+	PluginName string `json:"-"`
 }
 
 func (c *RenderTemplatesPlugin) Name() string {
 	return c.PluginName
 }
 
+func (c *RenderTemplatesPlugin) Requires() []string {
+	return []string{"readPosts"}
+}
+
 func SortPosts(posts []models.Post) []models.Post {
 	sort.Slice(posts, func(i, j int) bool {
 		if posts[i].Frontmatter.Date == "" {
@@ -394,80 +436,50 @@ func SortPosts(posts []models.Post) []models.Post {
 	return posts
 }
 
-type oEmbedResponse struct {
-	HTML string `json:"html"`
-}
-
-func getTwitterEmbed(url string) string {
-	apiURL := "https://publish.twitter.com/oembed?url=" + url
-	resp, err := http.Get(apiURL)
-	if err != nil {
-		fmt.Println("Error fetching Twitter embed:", err)
-		return fmt.Sprintf(`<div class="embed-container"><a href="%s" target="_blank">%s</a></div>`, url, url)
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	var oembed oEmbedResponse
-	if err := json.Unmarshal(body, &oembed); err != nil {
-		fmt.Println("Error parsing Twitter embed response:", err)
-		return fmt.Sprintf(`<div class="embed-container"><a href="%s" target="_blank">%s</a></div>`, url, url)
-	}
-
-	return fmt.Sprintf(`<div class="embed-container">%s</div>`, oembed.HTML)
-}
-
-func generateEmbed(postContent string) string {
-	re := regexp.MustCompile(`{%\s*embed\s*(https?://[^\s]+)\s*%}`)
-	replacedContent := re.ReplaceAllStringFunc(postContent, func(match string) string {
-		url := re.FindStringSubmatch(match)[1]
-
-		if strings.Contains(url, "x.com") || strings.Contains(url, "twitter.com") {
-			return getTwitterEmbed(url)
-		}
-		if strings.Contains(url, "youtube.com") || strings.Contains(url, "youtu.be") {
-			videoID := extractYouTubeID(url)
-			return fmt.Sprintf(`<iframe width="560" height="315" src="https://www.youtube.com/embed/%s" frameborder="0" allowfullscreen></iframe>`, videoID)
-		}
-		return fmt.Sprintf(`<iframe src="%s" width="600" height="400" frameborder="0"></iframe>`, url)
-	})
-	return replacedContent
-}
-
-func extractYouTubeID(url string) string {
-	if strings.Contains(url, "youtube.com/watch?v=") {
-		return strings.Split(strings.TrimPrefix(url, "https://www.youtube.com/watch?v="), "&")[0]
-	}
-	if strings.Contains(url, "youtu.be/") {
-		return strings.Split(strings.TrimPrefix(url, "https://youtu.be/"), "?")[0]
-	}
-	return ""
-}
-
-func (c *RenderTemplatesPlugin) Execute(ssg *models.SSG) {
+func (c *RenderTemplatesPlugin) Execute(ssg *models.SSG) error {
 	config := &ssg.Config
 	templateFS := os.DirFS(config.Blog.TemplatesDir)
 	ssg.FS = templateFS
-	t, err := template.ParseFS(templateFS, "*.html")
+	t, err := template.New("").Funcs(plugins.TemplateFuncs).Funcs(plugins.AssetFuncs(ssg.Assets, config.Blog)).ParseFS(templateFS, "*.html")
 	ssg.TemplateFS = t
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	ssg.TemplateHash, err = cache.HashDir(config.Blog.TemplatesDir)
+	if err != nil {
+		return err
+	}
+	// the pages change with the URLs of the fingerprinted assets
+	ssg.TemplateHash = cache.Hash(ssg.TemplateHash, ssg.Assets.Key())
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	// minified pages differ from the ones of the dev server
+	ssg.ConfigHash = cache.Hash(string(configBytes), strconv.FormatBool(config.Dev))
 	var prefixURL string = ""
 	if config.Blog.PrefixURL != "" {
 		prefixURL = config.Blog.PrefixURL
 	}
 
 	// render the templates with the content
-	outputPath := filepath.Join(".", config.Blog.OutputDir)
+	outputPath := filepath.Join(config.Blog.OutputDir)
 	err = os.MkdirAll(outputPath, os.ModePerm)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	feedPosts := make(map[string][]models.Post)
 	postsCopy := slices.Clone(ssg.Posts)
 	SortPosts(postsCopy)
 	ssg.Posts = postsCopy
+	jobs := []plugins.RenderJob{}
+	type page struct {
+		post     models.Post
+		template string
+		output   string
+	}
+	pages := []page{}
+	last := make(map[string]int)
 
 	for _, post := range ssg.Posts {
 		fmt.Println("Post:", post.Frontmatter.Title, post.Frontmatter.Type)
@@ -485,18 +497,12 @@ func (c *RenderTemplatesPlugin) Execute(ssg *models.SSG) {
 		if templatePath == "" {
 			templatePath = config.Blog.DefaultPostTemplate
 		}
-		buffer := bytes.Buffer{}
 		postSlug := post.Frontmatter.Slug
 		if postSlug == "" {
 			postSlug = plugins.Slugify(post.Frontmatter.Title)
 		}
 		post.Frontmatter.Slug = prefixURL + postType + "/" + postSlug
 		postPath := filepath.Join(outputPath, postType, postSlug)
-		//outputDirPath := filepath.Join(postPath, postSlug)
-		err = os.MkdirAll(postPath, os.ModePerm)
-		if err != nil {
-			log.Fatal(err)
-		}
 		outputPostPath := filepath.Join(postPath, "index.html")
 		for i := range ssg.Posts {
 			if ssg.Posts[i].Frontmatter.Date == "" {
@@ -504,9 +510,21 @@ func (c *RenderTemplatesPlugin) Execute(ssg *models.SSG) {
 			}
 			ssg.Posts[i].Frontmatter.Date = ssg.Posts[i].Frontmatter.Date[:10] // Truncate in case of time component
 		}
-		post.Content = template.HTML(generateEmbed(string(post.Content)))
+		feedPosts[postType] = append(feedPosts[postType], post)
+		pages = append(pages, page{post: post, template: templatePath, output: outputPostPath})
+		last[filepath.Clean(outputPostPath)] = len(pages) - 1
+	}
+	for i, page := range pages {
+		// like RenderPages, only the last post written to a path counts
+		post := page.post
+		key := plugins.PostKey(ssg, page.template, post)
+		if last[filepath.Clean(page.output)] != i {
+			continue
+		}
 		context := models.TemplateContext{
-			Post: post,
+			Post:    post,
+			Series:  ssg.Series[post.SourcePath],
+			Related: ssg.Related[post.SourcePath],
 			Themes: models.ThemeCombo{
 				Default:   config.Blog.Themes["default"],
 				Secondary: config.Blog.Themes["secondary"],
@@ -514,15 +532,22 @@ func (c *RenderTemplatesPlugin) Execute(ssg *models.SSG) {
 			Config: models.SSG_CONFIG{
 				Blog:      config.Blog,
 				AdminMode: config.AdminMode,
+				Markdown:  config.Markdown,
 			},
 		}
-		err := t.ExecuteTemplate(&buffer, templatePath, context)
-		if err != nil {
-			log.Fatal(err)
+		if ssg.Cache.Fresh(page.output, key) {
+			ssg.Manifest.Add(plugins.PageManifest(page.output, context))
+			continue
 		}
-		err = os.WriteFile(outputPostPath, buffer.Bytes(), 0660)
-		feedPosts[postType] = append(feedPosts[postType], post)
+		jobs = append(jobs, plugins.RenderJob{
+			Template:   page.template,
+			Context:    context,
+			OutputPath: page.output,
+			Key:        key,
+		})
 	}
+	// the feeds are still built when some posts fail to render
+	renderErr := errors.Join(plugins.RenderPages(ssg, jobs), plugins.CopyBundles(ssg))
 	for postType, posts := range feedPosts {
 		fmt.Println(postType)
 		fmt.Println(len(posts))
@@ -545,61 +570,61 @@ func (c *RenderTemplatesPlugin) Execute(ssg *models.SSG) {
 		fmt.Println("Feed", feed.Title, feed.Slug)
 		feedPostLists = append(feedPostLists, feed)
 	}
+	slices.SortFunc(feedPostLists, func(a, b models.Feed) int {
+		return strings.Compare(a.Type, b.Type)
+	})
 	ssg.FeedPosts = feedPostLists
+	return renderErr
 }
 
 // "createFeeds",
 type CreateFeedsPlugin struct {
-	PluginName string
+	PluginName string `json:"-"`
 }
 
 func (c *CreateFeedsPlugin) Name() string {
 	return c.PluginName
 }
 
-func (c *CreateFeedsPlugin) Execute(ssg *models.SSG) {
+func (c *CreateFeedsPlugin) Requires() []string {
+	return []string{"renderTemplates"}
+}
+
+func (c *CreateFeedsPlugin) Execute(ssg *models.SSG) error {
 	config := &ssg.Config
+	jobs := []plugins.RenderJob{}
 	for _, feed := range ssg.FeedPosts {
-		buffer := bytes.Buffer{}
 		templatePath := config.Blog.PagesConfig[feed.Type].FeedTemplatePath
 		if templatePath == "" {
 			templatePath = config.Blog.DefaultFeedTemplate
 		}
+		feedPath := filepath.Join(config.Blog.OutputDir, feed.Type)
 
 		context := models.TemplateContext{
-			FeedPosts: []models.Feed{feed},
 			Themes: models.ThemeCombo{
 				Default:   config.Blog.Themes["default"],
 				Secondary: config.Blog.Themes["secondary"],
 			},
-			FeedInfo: feed,
 			Config: models.SSG_CONFIG{
 				Blog:      config.Blog,
 				AdminMode: config.AdminMode,
+				Markdown:  config.Markdown,
 			},
 		}
 		fmt.Println("Post:", feed.Title, len(feed.Posts))
-		err := ssg.TemplateFS.ExecuteTemplate(&buffer, templatePath, context)
-		if err != nil {
-			log.Fatal(err)
-		}
-		feedPath := filepath.Join(".", config.Blog.OutputDir, feed.Type)
-		err = os.MkdirAll(feedPath, os.ModePerm)
-		outputFeedPath := fmt.Sprintf("%s/index.html", feedPath)
-		err = os.WriteFile(outputFeedPath, buffer.Bytes(), 0660)
+		pageSize := config.Blog.PagesConfig[feed.Type].PageSize
+		jobs = append(jobs, plugins.FeedPageJobs(ssg, templatePath, feed, pageSize, feedPath, context)...)
 	}
 	// create a folder for post if the post type is "post"
 	// as this should be the /posts/<slug> as well as /<slug>
 	for _, post := range ssg.Posts {
 		if post.Frontmatter.Type == "posts" {
-			postPath := filepath.Join(".", config.Blog.OutputDir, post.Frontmatter.Slug)
-			err := os.MkdirAll(postPath, os.ModePerm)
-			if err != nil {
-				log.Fatal(err)
-			}
-			buffer := bytes.Buffer{}
+			postPath := filepath.Join(config.Blog.OutputDir, post.Frontmatter.Slug)
+			outputPostPath := fmt.Sprintf("%s/index.html", postPath)
 			context := models.TemplateContext{
-				Post: post,
+				Post:    post,
+				Series:  ssg.Series[post.SourcePath],
+				Related: ssg.Related[post.SourcePath],
 				Themes: models.ThemeCombo{
 					Default:   config.Blog.Themes["default"],
 					Secondary: config.Blog.Themes["secondary"],
@@ -607,58 +632,103 @@ func (c *CreateFeedsPlugin) Execute(ssg *models.SSG) {
 				Config: models.SSG_CONFIG{
 					Blog:      config.Blog,
 					AdminMode: config.AdminMode,
+					Markdown:  config.Markdown,
 				},
 			}
-			err = ssg.TemplateFS.ExecuteTemplate(&buffer, config.Blog.DefaultPostTemplate, context)
-			if err != nil {
-				log.Fatal(err)
-			}
-			outputPostPath := fmt.Sprintf("%s/index.html", postPath)
-			err = os.WriteFile(outputPostPath, buffer.Bytes(), 0660)
-			if err != nil {
-				log.Fatal(err)
-			}
+			jobs = append(jobs, plugins.RenderJob{
+				Template:   config.Blog.DefaultPostTemplate,
+				Context:    context,
+				OutputPath: outputPostPath,
+				Key:        plugins.PostKey(ssg, config.Blog.DefaultPostTemplate, post),
+			})
 		}
 	}
-
+	return plugins.RenderPages(ssg, jobs)
 }
 
 // "createFeeds",
 // "copyStaticFiles",
 type CopyStaticFilesPlugin struct {
-	PluginName string
+	PluginName string `json:"-"`
 }
 
 func (c *CopyStaticFilesPlugin) Name() string {
 	return c.PluginName
 }
 
-func (c *CopyStaticFilesPlugin) Execute(ssg *models.SSG) {
-	config := &ssg.Config
-	err := Copy(config.Blog.StaticDir, config.Blog.OutputDir)
-	err = GeneratePages(*config)
+// BeforeRender scans the static dir, and adds the stylesheets generated
+// from the config, before the pages are rendered, so that the templates can
+// link to the fingerprinted assets.
+func (c *CopyStaticFilesPlugin) BeforeRender(ssg *models.SSG) error {
+	var err error
+	ssg.Assets, err = assets.Scan(ssg.Config.Blog.StaticDir, ssg.Config.Assets, plugins.MinifyAsset(ssg.Config))
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	ssg.Assets.Add(plugins.ThemeStylesheet, plugins.ThemeCSS(ssg.Config.Blog.Themes))
+	if ssg.Config.Markdown.Highlight {
+		ssg.Assets.Add(plugins.HighlightStylesheet, plugins.HighlightCSS())
 	}
+	return nil
+}
+
+func (c *CopyStaticFilesPlugin) Execute(ssg *models.SSG) error {
+	config := &ssg.Config
+	copied := 0
+	for _, asset := range ssg.Assets.Assets() {
+		for _, output := range asset.Outputs() {
+			dst := filepath.Join(config.Blog.OutputDir, filepath.FromSlash(output))
+			if ssg.Cache.Fresh(dst, asset.Hash) {
+				continue
+			}
+			ok := true
+			var err error
+			if asset.Data != nil {
+				err = os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+				if err == nil {
+					err = os.WriteFile(dst, asset.Data, 0660)
+				}
+				if len(asset.Data) != asset.Size {
+					ssg.Minified.Add(asset.Size, len(asset.Data))
+				}
+			} else {
+				ok, err = assets.CopyFile(asset.Source, dst)
+			}
+			if err != nil {
+				return plugins.PageError(asset.Source, dst, err)
+			}
+			if ok {
+				copied++
+			}
+			ssg.Cache.Record(dst, asset.Hash)
+		}
+	}
+	fmt.Println("Static files copied:", copied)
+	return GeneratePages(ssg)
 }
 
 type IndexPlugin struct {
-	PluginName string
+	PluginName string `json:"-"`
 }
 
 func (c *IndexPlugin) Name() string {
 	return c.PluginName
 }
 
-func (c *IndexPlugin) Execute(ssg *models.SSG) {
+func (c *IndexPlugin) Requires() []string {
+	return []string{"renderTemplates"}
+}
+
+func (c *IndexPlugin) Execute(ssg *models.SSG) error {
 	config := &ssg.Config
 
 	buffer := bytes.Buffer{}
 	templateFS := os.DirFS(config.Blog.StaticDir)
-	t, err := template.ParseFS(templateFS, "index.html")
+	t, err := template.New("index.html").Funcs(plugins.TemplateFuncs).Funcs(plugins.AssetFuncs(ssg.Assets, config.Blog)).ParseFS(templateFS, "index.html")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	outputIndexPath := filepath.Join(config.Blog.OutputDir, "index.html")
 	context := models.TemplateContext{
 		Themes: models.ThemeCombo{
 			Default:   config.Blog.Themes["default"],
@@ -669,69 +739,146 @@ func (c *IndexPlugin) Execute(ssg *models.SSG) {
 			AdminMode: config.AdminMode,
 		},
 		FeedPosts: ssg.FeedPosts,
+		Feeds:     ssg.FeedLinks[config.Blog.PrefixURL],
 	}
 	err = t.ExecuteTemplate(&buffer, "index.html", context)
 	if err != nil {
-		log.Fatal(err)
+		return plugins.PageError("", outputIndexPath, err)
 	}
-	err = os.WriteFile(filepath.Join(".", config.Blog.OutputDir, "index.html"), buffer.Bytes(), 0660)
+	err = plugins.WritePage(ssg, outputIndexPath, buffer.Bytes())
 	if err != nil {
-		log.Fatal(err)
+		return plugins.PageError("", outputIndexPath, err)
 	}
+	ssg.Manifest.Add(plugins.PageManifest(outputIndexPath, context))
+	return nil
 }
 
 // admin
 type AdminPlugin struct {
-	PluginName string
+	PluginName string `json:"-"`
 }
 
 func (c *AdminPlugin) Name() string {
 	return c.PluginName
 }
 
-func (c *AdminPlugin) Execute(ssg *models.SSG) {
+func (c *AdminPlugin) Execute(ssg *models.SSG) error {
+	return nil
 }
 
 // "server"
 type ServerPlugin struct {
-	PluginName string
+	PluginName string       `json:"-"`
+	Host       string       `json:"host"`
+	Port       int          `json:"port"`
+	Flags      *GlobalFlags `json:"-"`
+	// Offline embeds links with the cached oEmbed responses only
+	Offline bool `json:"-"`
 }
 
 func (c *ServerPlugin) Name() string {
 	return c.PluginName
 }
 
-func (c *ServerPlugin) Execute(ssg *models.SSG) {
-	config := &ssg.Config
-	http.Handle("/", http.FileServer(http.Dir(config.Blog.OutputDir)))
-	fmt.Println("Listening on port 3030")
-	http.ListenAndServe(":3030", nil)
-}
-
-func main() {
-
-	args := os.Args
-	devEnv := false
-	if len(args) > 1 {
-		if args[1] == "dev" {
-			devEnv = true
+// Execute builds the site, serves the output directory and rebuilds the
+// site whenever a post, template, static file or the config changes.
+// Failed builds are shown in the browser instead of stopping the server.
+func (c *ServerPlugin) Execute(ssg *models.SSG) error {
+	config := ssg.Config
+	server := devserver.NewServer(config.Blog.OutputDir)
+	watcher := devserver.NewWatcher(300*time.Millisecond,
+		config.Blog.PostsDir, config.Blog.TemplatesDir, config.Blog.StaticDir, c.Flags.ConfigPath)
+	c.build(server, false)
+	go func() {
+		for changed := range watcher.Watch() {
+			fmt.Println("Changed:", strings.Join(changed, ", "))
+			staticChanged := slices.ContainsFunc(changed, func(file string) bool {
+				return devserver.Within(file, config.Blog.StaticDir)
+			})
+			c.build(server, !staticChanged)
+		}
+	}()
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	fmt.Println("Listening on", addr)
+	return http.ListenAndServe(addr, server)
+}
+
+// build rereads the config, builds the site for dev and hands the outcome
+// to server.
+func (c *ServerPlugin) build(server *devserver.Server, lazyAdmin bool) {
+	start := time.Now()
+	config, err := c.Flags.LoadConfig()
+	if err != nil {
+		log.Println(err)
+		server.Reload([]error{err})
+		return
+	}
+	config.Blog.PrefixURL = ""
+	config.Dev = true
+	options := BuildOptions{
+		KeepGoing:     true,
+		LazyAdmin:     lazyAdmin,
+		CacheDir:      c.Flags.CacheDir(),
+		EmbedCacheDir: c.Flags.EmbedCacheDir(),
+		Offline:       c.Offline,
+	}
+	diagnostics := Build(config, options)
+	if len(diagnostics) > 0 {
+		PrintDiagnostics(diagnostics)
+	}
+	errs := []error{}
+	for _, diagnostic := range diagnostics {
+		errs = append(errs, diagnostic)
+	}
+	fmt.Printf("Built in %v\n", time.Since(start).Round(time.Millisecond))
+	server.Reload(errs)
+}
+
+type BuildOptions struct {
+	// KeepGoing builds every page that can be built instead of stopping
+	// at the first plugin that fails.
+	KeepGoing bool
+	// LazyAdmin skips the admin pass when the site pass rendered no page,
+	// as nothing in the admin copy can have changed either.
+	LazyAdmin bool
+	// CacheDir is where the build cache is kept, nothing is cached if it
+	// is empty.
+	CacheDir string
+	// EmbedCacheDir is where the oEmbed responses are kept, they are
+	// fetched on every build if it is empty.
+	EmbedCacheDir string
+	// Offline embeds links with the responses in EmbedCacheDir only,
+	// falling back to a plain link for the others.
+	Offline bool
+}
+
+// Build builds the site described by config, then its admin copy, and
+// returns everything that went wrong. The build stops at the first plugin
+// that fails unless options.KeepGoing is set.
+func Build(config models.SSG_CONFIG, options BuildOptions) []*plugins.Diagnostic {
+	ssg := models.SSG{Config: config, Manifest: &models.Manifest{}, Minified: &minify.Report{}}
+	diagnostics := []*plugins.Diagnostic{}
+	var err error
+	ssg.Markdown, err = NewRenderer(config, oembed.NewClient(config.Oembed, options.EmbedCacheDir, options.Offline))
+	if err != nil {
+		return append(diagnostics, &plugins.Diagnostic{Err: err})
+	}
+	if options.CacheDir != "" {
+		ssg.Cache, err = cache.Open(options.CacheDir)
+		if err != nil {
+			return append(diagnostics, &plugins.Diagnostic{Err: err})
 		}
 	}
-	// read the config
-	// read all the plugins from config file
-	ssg := models.SSG{}
-	configbytes, err := os.ReadFile(models.SSG_CONFIG_FILE_NAME)
-	if err != nil {
-		log.Fatal(err)
+	execute := func(pluginManager *PluginManager) bool {
+		pluginManager.KeepGoing = options.KeepGoing
+		err := pluginManager.ExecuteAll(&ssg)
+		diagnostics = append(diagnostics, pluginManager.Diagnostics...)
+		return err == nil || options.KeepGoing
 	}
-	var config models.SSG_CONFIG
-	err = json.Unmarshal(configbytes, &config)
+	pluginManager := PluginManager{}
+	err = RegisterPlugins(&pluginManager, config.Plugins)
 	if err != nil {
-		log.Fatal(err)
-	}
-	ssg.Config = config
-	if devEnv {
-		ssg.Config.Blog.PrefixURL = ""
+		return append(diagnostics, &plugins.Diagnostic{Err: err})
 	}
 
 	// loading in the posts -> post folder
@@ -739,99 +886,126 @@ func main() {
 	// create feeds
 	// load in the static files
 	// pack the html pages and static files in a folder
-	// serve the folder
-	pluginManager := PluginManager{}
-	for _, plugin := range config.Plugins {
-		switch plugin {
-		case "readPosts":
-			pluginManager.Register(&PostReaderPlugin{PluginName: "readPosts"})
-		case "renderTemplates":
-			pluginManager.Register(&RenderTemplatesPlugin{PluginName: "renderTemplates"})
-		case "createFeeds":
-			pluginManager.Register(&CreateFeedsPlugin{PluginName: "createFeeds"})
-		case "copyStaticFiles":
-			pluginManager.Register(&CopyStaticFilesPlugin{PluginName: "copyStaticFiles"})
-		case "index":
-			pluginManager.Register(&IndexPlugin{PluginName: "index"})
-		default:
-
-			//userPlugin := plugins.UserPlugin{PluginName: plugin}
-			//pluginManager.Register(&userPlugin)
-
-			if plugin != "server" {
-				pluginStruct, err := LoadPlugin(plugin)
-				if err != nil {
-					log.Printf("Error loading plugin %s: %v", plugin, err)
-					continue
-				}
-				fmt.Println("Load", plugin, pluginStruct)
-				pluginManager.Register(pluginStruct)
-			} else {
-				continue
-			}
+	if !execute(&pluginManager) {
+		return diagnostics
+	}
+	adminDir := path.Join(config.Blog.OutputDir, config.Blog.AdminDir)
+	if options.LazyAdmin && ssg.Cache != nil && ssg.Cache.Rendered() == 0 {
+		ssg.Cache.Keep(adminDir)
+	} else {
+		ssg.Config.AdminMode = true
+		ssg.Config.Blog.OutputDir = adminDir
+		pluginManager = PluginManager{}
+		err = RegisterPlugins(&pluginManager, config.Plugins)
+		if err != nil {
+			return append(diagnostics, &plugins.Diagnostic{Err: err})
+		}
+		if !execute(&pluginManager) {
+			return diagnostics
+		}
+		pluginManager = PluginManager{}
+		pluginManager.Register(&AdminPlugin{PluginName: "admin"})
+		if !execute(&pluginManager) {
+			return diagnostics
 		}
 	}
-	pluginManager.ExecuteAll(&ssg)
-	pluginManager = PluginManager{}
-	originalOutputDir := ssg.Config.Blog.OutputDir
-	ssg.Config.AdminMode = true
-	ssg.Config.Blog.OutputDir = path.Join(ssg.Config.Blog.OutputDir, ssg.Config.Blog.AdminDir)
-	for _, plugin := range config.Plugins {
-		switch plugin {
-		case "readPosts":
-			pluginManager.Register(&PostReaderPlugin{PluginName: "readPosts"})
-		case "renderTemplates":
-			pluginManager.Register(&RenderTemplatesPlugin{PluginName: "renderTemplates"})
-		case "createFeeds":
-			pluginManager.Register(&CreateFeedsPlugin{PluginName: "createFeeds"})
-		case "copyStaticFiles":
-			pluginManager.Register(&CopyStaticFilesPlugin{PluginName: "copyStaticFiles"})
-		case "index":
-			pluginManager.Register(&IndexPlugin{PluginName: "index"})
-		default:
-
-			//userPlugin := plugins.UserPlugin{PluginName: plugin}
-			//pluginManager.Register(&userPlugin)
-
-			if plugin != "server" {
-				pluginStruct, err := LoadPlugin(plugin)
-				if err != nil {
-					log.Printf("Error loading plugin %s: %v", plugin, err)
-					continue
-				}
-				fmt.Println("Load", plugin, pluginStruct)
-				pluginManager.Register(pluginStruct)
-			} else {
-				continue
-			}
+	// outputs of pages that failed are kept until they build again
+	if len(diagnostics) == 0 {
+		removed, err := ssg.Cache.Prune()
+		if err != nil {
+			return append(diagnostics, &plugins.Diagnostic{Err: err})
+		}
+		for _, output := range removed {
+			fmt.Println("Removed stale output:", output)
 		}
 	}
-	pluginManager.ExecuteAll(&ssg)
-	pluginManager = PluginManager{}
-	pluginManager.Register(&AdminPlugin{PluginName: "admin"})
-	pluginManager.ExecuteAll(&ssg)
-	ssg.Config.Blog.OutputDir = originalOutputDir
-	pluginManager = PluginManager{}
-	if devEnv {
-		pluginManager.Register(&ServerPlugin{PluginName: "server"})
+	if config.Minify != (minify.Config{}) && !config.Dev {
+		fmt.Println("Minify:", ssg.Minified)
 	}
-	pluginManager.ExecuteAll(&ssg)
+	err = ssg.Cache.Save()
+	if err != nil {
+		diagnostics = append(diagnostics, &plugins.Diagnostic{Err: err})
+	}
+	return diagnostics
 }
 
-func LoadPlugin(pluginName string) (plugins.Plugin, error) {
-	pluginType, exists := plugins.GetPluginType(pluginName)
-	if !exists {
-		return nil, fmt.Errorf("plugin %s not found", pluginName)
-	}
+// builtinPlugins are the plugins of the plugins list created by LoadPlugin
+// without a registry entry, and the admin plugin of the admin pass.
+var builtinPlugins = []string{"readPosts", "renderTemplates", "createFeeds", "copyStaticFiles", "index", "server", "admin"}
+
+func init() {
+	models.PluginNames = append(slices.Clone(builtinPlugins), plugins.PluginNames()...)
+}
 
-	pluginValue := reflect.New(pluginType).Interface()
-	pluginInstance, ok := pluginValue.(plugins.Plugin)
-	if !ok {
-		return nil, fmt.Errorf("type %s does not implement Plugin interface", pluginName)
+// RegisterPlugins loads and registers the plugins of the plugins list,
+// except the server which only runs in dev and the admin plugin which
+// only runs in the admin pass.
+func RegisterPlugins(pluginManager *PluginManager, entries []models.PluginConfig) error {
+	for _, entry := range entries {
+		if entry.Name == "server" || entry.Name == "admin" {
+			continue
+		}
+		plugin, err := LoadPlugin(entry)
+		if errors.Is(err, errPluginNotFound) {
+			return fmt.Errorf("plugin %s: %w", entry.Name, err)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println("Load", entry.Name, plugin)
+		pluginManager.Register(plugin)
 	}
-	val := reflect.ValueOf(pluginInstance).Elem()
-	if field := val.FieldByName("PluginName"); field.IsValid() && field.CanSet() {
-		field.SetString(pluginName)
+	return nil
+}
+
+var errPluginNotFound = errors.New("plugin not found")
+
+// LoadPlugin creates the plugin named by entry and decodes its options
+// into the fields of the plugin struct. Options the plugin has no field
+// for are an error.
+func LoadPlugin(entry models.PluginConfig) (plugins.Plugin, error) {
+	pluginName := entry.Name
+	var pluginInstance plugins.Plugin
+	switch pluginName {
+	case "readPosts":
+		pluginInstance = &PostReaderPlugin{PluginName: "readPosts"}
+	case "renderTemplates":
+		pluginInstance = &RenderTemplatesPlugin{PluginName: "renderTemplates"}
+	case "createFeeds":
+		pluginInstance = &CreateFeedsPlugin{PluginName: "createFeeds"}
+	case "copyStaticFiles":
+		pluginInstance = &CopyStaticFilesPlugin{PluginName: "copyStaticFiles"}
+	case "index":
+		pluginInstance = &IndexPlugin{PluginName: "index"}
+	case "server":
+		pluginInstance = &ServerPlugin{
+			PluginName: "server",
+			Port:       3030,
+			Flags:      &GlobalFlags{ConfigPath: models.SSG_CONFIG_FILE_NAME},
+		}
+	default:
+		pluginType, exists := plugins.GetPluginType(pluginName)
+		if !exists {
+			return nil, errPluginNotFound
+		}
+
+		pluginValue := reflect.New(pluginType).Interface()
+		var ok bool
+		pluginInstance, ok = pluginValue.(plugins.Plugin)
+		if !ok {
+			return nil, fmt.Errorf("type %s does not implement Plugin interface", pluginName)
+		}
+		val := reflect.ValueOf(pluginInstance).Elem()
+		if field := val.FieldByName("PluginName"); field.IsValid() && field.CanSet() {
+			field.SetString(pluginName)
+		}
+	}
+	if len(entry.Options) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(entry.Options))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(pluginInstance); err != nil {
+			return nil, fmt.Errorf("plugin %s options: %w", pluginName, err)
+		}
 	}
 	return pluginInstance, nil
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// testSite writes a site with the posts given by path under the posts
+// dir, using the config, templates and static files of the repository
+// with only the plugins building pages, and returns its config.
+func testSite(t *testing.T, posts map[string]string) models.SSG_CONFIG {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range posts {
+		path := filepath.Join(dir, "posts", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config, err := models.LoadConfig(models.SSG_CONFIG_FILE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []*string{&config.Blog.TemplatesDir, &config.Blog.StaticDir} {
+		if *d, err = filepath.Abs(*d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config.Blog.PostsDir = filepath.Join(dir, "posts")
+	config.Blog.OutputDir = filepath.Join(dir, "public")
+	config.Plugins = nil
+	for _, name := range []string{"readPosts", "renderTemplates", "createFeeds", "copyStaticFiles"} {
+		config.Plugins = append(config.Plugins, models.PluginConfig{Name: name})
+	}
+	return config
+}
+
+func TestBuildHighlightLinks(t *testing.T) {
+	post := "---\ntitle: Code\ndate: 2024-01-31\nslug: code\ntype: posts\n---\n\n```go\nfunc main() {}\n```\n"
+	tests := []struct {
+		name      string
+		highlight bool
+	}{
+		{"highlighted", true},
+		{"not highlighted", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := testSite(t, map[string]string{"posts/code.md": post})
+			config.Markdown.Highlight = test.highlight
+			for _, diagnostic := range Build(config, BuildOptions{LazyAdmin: true}) {
+				t.Fatal(diagnostic)
+			}
+			// the page of the post and its copy at the root of the site
+			for _, page := range []string{"posts/code/index.html", "code/index.html"} {
+				html, err := os.ReadFile(filepath.Join(config.Blog.OutputDir, filepath.FromSlash(page)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if linked := strings.Contains(string(html), `href="/highlight.`); linked != test.highlight {
+					t.Errorf("%s links highlight.css: %v, want %v", page, linked, test.highlight)
+				}
+			}
+		})
+	}
+}
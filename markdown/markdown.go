@@ -0,0 +1,153 @@
+// Package markdown builds the goldmark renderer every post is converted
+// with, so that a post renders the same in the static build and in the
+// Netlify functions.
+package markdown
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/mr-destructive/mr-destructive.github.io/cache"
+	"github.com/mr-destructive/mr-destructive.github.io/shortcode"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// Config selects the goldmark extensions, it is the "markdown" section of
+// the config file.
+type Config struct {
+	// GFM turns on tables, strikethrough, task lists and autolinks
+	GFM            bool `json:"gfm"`
+	Footnote       bool `json:"footnote"`
+	Typographer    bool `json:"typographer"`
+	DefinitionList bool `json:"definition_list"`
+	// HeadingIDs gives every heading an id made from its text
+	HeadingIDs bool `json:"heading_ids"`
+	// Unsafe renders the raw HTML of posts instead of leaving it out, the
+	// HTML of shortcodes is rendered either way
+	Unsafe bool `json:"unsafe"`
+	// Highlight colours fenced code blocks at build time with the classes
+	// of the stylesheet generated from the theme code colours
+	Highlight bool `json:"highlight"`
+	// LineNumbers numbers the lines of every highlighted block, a single
+	// block asks for them with {linenos=true} after its language
+	LineNumbers bool `json:"line_numbers"`
+}
+
+// DefaultConfig is used for the keys the "markdown" section leaves out,
+// every extension is on but raw HTML is left out.
+var DefaultConfig = Config{
+	GFM:            true,
+	Footnote:       true,
+	Typographer:    true,
+	DefinitionList: true,
+	HeadingIDs:     true,
+	Highlight:      true,
+}
+
+// Renderer converts markdown to HTML.
+type Renderer struct {
+	goldmark.Markdown
+	// Shortcodes expands the shortcodes of the markdown before Render
+	// converts it, if set
+	Shortcodes *shortcode.Engine
+	// Key is the hash of the config and of the shortcodes, the same
+	// markdown rendered by renderers with the same Key gives the same HTML
+	Key string
+}
+
+// New returns a renderer with the extensions config turns on.
+func New(config Config) *Renderer {
+	extensions := []goldmark.Extender{}
+	if config.GFM {
+		extensions = append(extensions, extension.GFM)
+	}
+	if config.Footnote {
+		extensions = append(extensions, extension.Footnote)
+	}
+	if config.Typographer {
+		extensions = append(extensions, extension.Typographer)
+	}
+	if config.DefinitionList {
+		extensions = append(extensions, extension.DefinitionList)
+	}
+	if config.Highlight {
+		// lines are picked with {hl_lines=[3,5]} after the language
+		extensions = append(extensions, highlighting.NewHighlighting(
+			highlighting.WithFormatOptions(
+				chromahtml.WithClasses(true),
+				chromahtml.WithLineNumbers(config.LineNumbers),
+			),
+		))
+	}
+	parserOptions := []parser.Option{}
+	if config.HeadingIDs {
+		parserOptions = append(parserOptions, parser.WithAutoHeadingID())
+	}
+	options := []goldmark.Option{goldmark.WithExtensions(extensions...)}
+	if config.Unsafe {
+		options = append(options, goldmark.WithRendererOptions(html.WithUnsafe()))
+	} else {
+		// the HTML of shortcodes is rendered all the same
+		parserOptions = append(parserOptions, parser.WithASTTransformers(util.Prioritized(trustShortcodes{}, 1000)))
+		options = append(options, goldmark.WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(trustedRenderer{}, 1000))))
+	}
+	options = append(options, goldmark.WithParserOptions(parserOptions...))
+	configBytes, _ := json.Marshal(config)
+	return &Renderer{Markdown: goldmark.New(options...), Key: cache.Hash(string(configBytes))}
+}
+
+// WithShortcodes makes r expand the shortcodes of engine, and returns r.
+func (r *Renderer) WithShortcodes(engine *shortcode.Engine) *Renderer {
+	r.Shortcodes = engine
+	r.Key = cache.Hash(r.Key, engine.Key)
+	return r
+}
+
+// Render expands the shortcodes of source, read from file where source
+// starts at line, and converts it to HTML. Output with stand-ins for some
+// shortcodes is converted too, and comes with their errors, which wrap
+// shortcode.ErrIncomplete.
+func (r *Renderer) Render(file string, line int, source []byte, w io.Writer) error {
+	var incomplete error
+	context := parser.NewContext()
+	if r.Shortcodes != nil {
+		expanded, generated, err := r.Shortcodes.Expand(file, line, source)
+		if err != nil && !errors.Is(err, shortcode.ErrIncomplete) {
+			return err
+		}
+		source, incomplete = expanded, err
+		context.Set(generatedKey, generated)
+	}
+	if err := r.Convert(source, w, parser.WithContext(context)); err != nil {
+		return err
+	}
+	return incomplete
+}
+
+// LoadConfig reads only the "markdown" section of the config file at path,
+// for the functions deployed without the rest of the site. A missing file
+// gives the default config.
+func LoadConfig(path string) (Config, error) {
+	config := struct {
+		Markdown Config `json:"markdown"`
+	}{Markdown: DefaultConfig}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config.Markdown, nil
+	}
+	if err != nil {
+		return config.Markdown, err
+	}
+	err = json.Unmarshal(data, &config)
+	return config.Markdown, err
+}
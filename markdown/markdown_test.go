@@ -0,0 +1,95 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/shortcode"
+)
+
+func TestRenderShortcodeHTML(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		unsafe bool
+		want   []string
+		absent []string
+	}{
+		{
+			name:   "inline shortcode",
+			source: "A video {{< youtube abc >}} here.\n",
+			want:   []string{`<iframe width="560" height="315" src="https://www.youtube.com/embed/abc"`, "</iframe> here."},
+			absent: []string{"raw HTML omitted"},
+		},
+		{
+			name:   "block shortcode",
+			source: "{{< figure src=\"/a.png\" caption=\"A & B\" >}}\n",
+			want:   []string{`<figure><img src="/a.png" alt="" loading="lazy"><figcaption>A &amp; B</figcaption></figure>`},
+			absent: []string{"raw HTML omitted"},
+		},
+		{
+			name:   "markdown content of a shortcode",
+			source: "{{< callout warning Careful >}}\nSome **markdown**.\n{{< /callout >}}\n",
+			want:   []string{`<div class="callout callout-warning">`, `<p class="callout-title">Careful</p>`, "<p>Some <strong>markdown</strong>.</p>", "</div>"},
+			absent: []string{"raw HTML omitted"},
+		},
+		{
+			name:   "nested shortcode",
+			source: "{{< details >}}\n{{< youtube abc >}}\n{{< /details >}}\n",
+			want:   []string{"<details>", "<summary>Details</summary>", "embed/abc", "</details>"},
+			absent: []string{"raw HTML omitted"},
+		},
+		{
+			name:   "raw HTML of the post",
+			source: "Some <b>bold</b> text.\n\n<div>block</div>\n",
+			want:   []string{"<!-- raw HTML omitted -->bold<!-- raw HTML omitted -->", "<!-- raw HTML omitted -->\n"},
+			absent: []string{"<b>", "<div>"},
+		},
+		{
+			name:   "raw HTML of the post inside a shortcode",
+			source: "{{< callout >}}\n<div>block</div>\n{{< /callout >}}\n",
+			want:   []string{`<div class="callout callout-note">`, "<!-- raw HTML omitted -->"},
+			absent: []string{"<div>block</div>"},
+		},
+		{
+			name:   "raw HTML of the post next to a shortcode",
+			source: "{{< youtube abc >}}\n<span>after</span>\n",
+			want:   []string{"embed/abc", "<!-- raw HTML omitted -->"},
+			absent: []string{"<span>"},
+		},
+		{
+			name:   "unsafe",
+			source: "{{< youtube abc >}}\n\n<div>block</div>\n",
+			unsafe: true,
+			want:   []string{"embed/abc", "<div>block</div>"},
+			absent: []string{"raw HTML omitted"},
+		},
+	}
+	engine, err := shortcode.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultConfig
+			config.Unsafe = test.unsafe
+			var output bytes.Buffer
+			err := New(config).WithShortcodes(engine).Render("post.md", 1, []byte(test.source), &output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			html := output.String()
+			for _, want := range test.want {
+				if !strings.Contains(html, want) {
+					t.Errorf("output does not contain %q:\n%s", want, html)
+				}
+			}
+			for _, absent := range test.absent {
+				if strings.Contains(html, absent) {
+					t.Errorf("output contains %q:\n%s", absent, html)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// generatedKey holds the byte ranges of the source written by shortcodes
+// in the parser context.
+var generatedKey = parser.NewContextKey()
+
+// kindTrustedHTMLBlock and kindTrustedRawHTML are the raw HTML written by
+// shortcodes, which is rendered even when the raw HTML of posts is left
+// out.
+var (
+	kindTrustedHTMLBlock = ast.NewNodeKind("TrustedHTMLBlock")
+	kindTrustedRawHTML   = ast.NewNodeKind("TrustedRawHTML")
+)
+
+// htmlLine is a line or an inline part of raw HTML, trusted when written
+// by a shortcode.
+type htmlLine struct {
+	segment text.Segment
+	trusted bool
+}
+
+// trustedHTMLBlock is an HTML block with lines written by shortcodes.
+type trustedHTMLBlock struct {
+	ast.BaseBlock
+	lines []htmlLine
+}
+
+func (n *trustedHTMLBlock) Kind() ast.NodeKind {
+	return kindTrustedHTMLBlock
+}
+
+func (n *trustedHTMLBlock) IsRaw() bool {
+	return true
+}
+
+func (n *trustedHTMLBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// trustedRawHTML is inline raw HTML written by a shortcode.
+type trustedRawHTML struct {
+	ast.BaseInline
+	lines []htmlLine
+}
+
+func (n *trustedRawHTML) Kind() ast.NodeKind {
+	return kindTrustedRawHTML
+}
+
+func (n *trustedRawHTML) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// trustShortcodes replaces the raw HTML written by shortcodes with trusted
+// nodes, the raw HTML of the post itself is kept for the renderer to leave
+// out.
+type trustShortcodes struct{}
+
+func (trustShortcodes) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	generated, _ := pc.Get(generatedKey).([][2]int)
+	if len(generated) == 0 {
+		return
+	}
+	source := reader.Source()
+	lines := func(segments *text.Segments) ([]htmlLine, bool) {
+		lines := []htmlLine{}
+		some := false
+		for i := range segments.Len() {
+			segment := segments.At(i)
+			trusted := inRanges(generated, source, segment)
+			lines = append(lines, htmlLine{segment, trusted})
+			some = some || trusted
+		}
+		return lines, some
+	}
+	replaced := map[ast.Node]ast.Node{}
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.HTMLBlock:
+			segments := text.NewSegments()
+			segments.AppendAll(n.Lines().Sliced(0, n.Lines().Len()))
+			if n.HasClosure() {
+				segments.Append(n.ClosureLine)
+			}
+			if lines, ok := lines(segments); ok {
+				replaced[n] = &trustedHTMLBlock{lines: lines}
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			if lines, ok := lines(n.Segments); ok {
+				replaced[n] = &trustedRawHTML{lines: lines}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	for old, node := range replaced {
+		old.Parent().ReplaceChild(old.Parent(), old, node)
+	}
+}
+
+// inRanges reports whether segment, but for the line break ending it, is
+// within one of ranges.
+func inRanges(ranges [][2]int, source []byte, segment text.Segment) bool {
+	stop := segment.Stop
+	for stop > segment.Start && (source[stop-1] == '\n' || source[stop-1] == '\r') {
+		stop--
+	}
+	for _, r := range ranges {
+		if segment.Start >= r[0] && stop <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedRenderer writes the trusted lines of the trusted nodes as they
+// are, and leaves out the others like the raw HTML of posts.
+type trustedRenderer struct{}
+
+func (trustedRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindTrustedHTMLBlock, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			writeLines(w, source, node.(*trustedHTMLBlock).lines, "<!-- raw HTML omitted -->\n")
+		}
+		return ast.WalkContinue, nil
+	})
+	reg.Register(kindTrustedRawHTML, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			writeLines(w, source, node.(*trustedRawHTML).lines, "<!-- raw HTML omitted -->")
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+func writeLines(w util.BufWriter, source []byte, lines []htmlLine, omitted string) {
+	for i, line := range lines {
+		switch {
+		case line.trusted:
+			_, _ = w.Write(line.segment.Value(source))
+		case i == 0 || lines[i-1].trusted:
+			_, _ = w.WriteString(omitted)
+		}
+	}
+}
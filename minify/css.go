@@ -0,0 +1,82 @@
+package minify
+
+import (
+	"bytes"
+	"strings"
+)
+
+// cssTight are the characters whitespace can be removed after; whitespace
+// before ':' is kept, as in "a :hover" it selects descendants.
+const cssTight = "{};,:>(!"
+
+// CSS minifies a stylesheet: it removes comments other than /*! ones,
+// collapses whitespace and drops it around punctuation, and drops the last
+// semicolon of each block. Strings are kept as they are.
+func CSS(src []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(src))
+	s := string(src)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := stringEnd(s, i)
+			out.WriteString(s[i:end])
+			i = end
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return out.Bytes()
+			}
+			end += i + 4
+			if strings.HasPrefix(s[i:], "/*!") {
+				out.WriteString(s[i:end])
+			}
+			i = end
+		case isSpace(c):
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			prev := lastByte(&out)
+			if prev == 0 || i == len(s) || strings.IndexByte(cssTight, prev) >= 0 || strings.IndexByte("{};,>)!", s[i]) >= 0 {
+				continue
+			}
+			out.WriteByte(' ')
+		case c == ';':
+			i++
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && s[i] == '}' {
+				continue
+			}
+			out.WriteByte(';')
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return bytes.TrimSpace(out.Bytes())
+}
+
+// stringEnd returns the index after the string literal starting at start,
+// skipping escaped quotes.
+func stringEnd(s string, start int) int {
+	quote := s[start]
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func lastByte(out *bytes.Buffer) byte {
+	if out.Len() == 0 {
+		return 0
+	}
+	return out.Bytes()[out.Len()-1]
+}
@@ -0,0 +1,167 @@
+package minify
+
+import (
+	"bytes"
+	"strings"
+)
+
+// jsTightAfter are the characters whitespace can be dropped after, and
+// jsTightBefore the ones it can be dropped before, when it holds a
+// newline: a newline after any other character may end a statement.
+const (
+	jsTightAfter  = "{([;,=:?&|!<>*%^~+-"
+	jsTightBefore = "})];,.:?=&|"
+)
+
+// JS minifies a script: it removes comments and collapses whitespace,
+// keeping the newlines that may end a statement. Strings, template
+// literals and regular expressions are kept as they are.
+func JS(src []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(src))
+	s := string(src)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			end := stringEnd(s, i)
+			out.WriteString(s[i:end])
+			i = end
+		case c == '/' && !strings.HasPrefix(s[i:], "/*!") && (strings.HasPrefix(s[i:], "//") || strings.HasPrefix(s[i:], "/*")) || isSpace(c):
+			// comments go like whitespace, a newline in either may end a
+			// statement
+			var newline bool
+			i, newline = skipSpace(s, i)
+			prev := lastByte(&out)
+			if prev == 0 || i == len(s) {
+				continue
+			}
+			next := s[i]
+			switch {
+			case clash(prev, next):
+				out.WriteByte(' ')
+			case newline:
+				if strings.IndexByte(jsTightAfter, prev) < 0 && strings.IndexByte(jsTightBefore, next) < 0 {
+					out.WriteByte('\n')
+				}
+			case isIdent(prev) && isIdent(next):
+				out.WriteByte(' ')
+			}
+		case strings.HasPrefix(s[i:], "/*!"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				end = len(s) - i - 4
+			}
+			out.WriteString(s[i : i+end+4])
+			i += end + 4
+		case c == '/' && regexpAllowed(out.Bytes()):
+			end := regexpEnd(s, i)
+			out.WriteString(s[i:end])
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return bytes.TrimSpace(out.Bytes())
+}
+
+// skipSpace returns the index after the whitespace and comments at start,
+// and whether they hold a newline.
+func skipSpace(s string, start int) (int, bool) {
+	newline := false
+	i := start
+	for i < len(s) {
+		switch {
+		case isSpace(s[i]):
+			newline = newline || s[i] == '\n' || s[i] == '\r'
+			i++
+		case strings.HasPrefix(s[i:], "//"):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				return len(s), newline
+			}
+			i += end
+		case strings.HasPrefix(s[i:], "/*") && !strings.HasPrefix(s[i:], "/*!"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return len(s), newline
+			}
+			newline = newline || strings.ContainsAny(s[i:i+end+2], "\n\r")
+			i += end + 4
+		default:
+			return i, newline
+		}
+	}
+	return i, newline
+}
+
+// clash reports whether prev and next would read as another token without
+// the whitespace between them, like "+ +" as "++".
+func clash(prev, next byte) bool {
+	switch {
+	case prev == '+' || prev == '-':
+		return next == prev
+	case prev == '/':
+		return next == '/' || next == '*'
+	case '0' <= prev && prev <= '9':
+		return next == '.'
+	}
+	return false
+}
+
+func isIdent(c byte) bool {
+	return isLetter(c) || '0' <= c && c <= '9' || c == '_' || c == '$' || c == '\\' || c >= 0x80
+}
+
+// regexpAllowed reports whether a / after out starts a regular expression
+// rather than a division: it does after punctuation and after the keywords
+// that take an expression.
+func regexpAllowed(out []byte) bool {
+	out = bytes.TrimRight(out, " \n")
+	if len(out) == 0 {
+		return true
+	}
+	last := out[len(out)-1]
+	if strings.IndexByte("(,=:[!&|?{};+-*%<>~^", last) >= 0 {
+		return true
+	}
+	if !isIdent(last) {
+		return false
+	}
+	start := len(out)
+	for start > 0 && isIdent(out[start-1]) {
+		start--
+	}
+	switch string(out[start:]) {
+	case "return", "typeof", "instanceof", "in", "of", "new", "delete", "void", "throw", "case", "do", "else", "yield", "await":
+		return true
+	}
+	return false
+}
+
+// regexpEnd returns the index after the regular expression literal at
+// start, with its flags.
+func regexpEnd(s string, start int) int {
+	class := false
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '\n':
+			return i
+		case '/':
+			if class {
+				continue
+			}
+			for i++; i < len(s) && isIdent(s[i]); i++ {
+			}
+			return i
+		}
+	}
+	return len(s)
+}
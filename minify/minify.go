@@ -0,0 +1,261 @@
+// Package minify shrinks the HTML pages, stylesheets and scripts of a site
+// without changing what they do. It only removes what is certain to be
+// unneeded: comments and the whitespace a browser would collapse or skip.
+// The contents of <pre>, <code> and <textarea> are never changed.
+package minify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Config is the "minify" section of the config file, what the build
+// minifies outside of the dev server.
+type Config struct {
+	// HTML minifies the pages, with their inline styles and scripts
+	HTML bool `json:"html"`
+	// CSS minifies the stylesheets of the static dir
+	CSS bool `json:"css"`
+	// JS minifies the scripts of the static dir
+	JS bool `json:"js"`
+}
+
+// Report counts the bytes minifying saved over a build. A nil Report
+// counts nothing, and it is safe for concurrent use.
+type Report struct {
+	mu     sync.Mutex
+	files  int
+	before int
+	after  int
+}
+
+// Add counts a file minified from before to after bytes.
+func (r *Report) Add(before, after int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files++
+	r.before += before
+	r.after += after
+}
+
+func (r *Report) String() string {
+	if r == nil {
+		return "nothing minified"
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.files == 0 {
+		return "nothing minified"
+	}
+	saved := r.before - r.after
+	return fmt.Sprintf("minified %d files from %s to %s, saved %s (%.1f%%)",
+		r.files, size(r.before), size(r.after), size(saved), 100*float64(saved)/float64(r.before))
+}
+
+func size(bytes int) string {
+	switch {
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(bytes)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
+
+// verbatim are the elements whose content is kept as it is.
+var verbatim = []string{"pre", "code", "textarea"}
+
+// HTML minifies an HTML document: it removes comments, collapses the
+// whitespace of text and tags, and minifies the content of <style> and of
+// the <script> elements holding JavaScript.
+func HTML(src []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(src))
+	s := string(src)
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			writeText(&out, s)
+			break
+		}
+		writeText(&out, s[:i])
+		s = s[i:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s, "-->")
+			if end < 0 {
+				out.WriteString(s)
+				return out.Bytes()
+			}
+			// conditional comments are markup for old browsers
+			if strings.HasPrefix(s, "<!--[if") {
+				out.WriteString(s[:end+3])
+			}
+			s = s[end+3:]
+			continue
+		case len(s) < 2 || !(isLetter(s[1]) || s[1] == '/' || s[1] == '!'):
+			out.WriteByte('<')
+			s = s[1:]
+			continue
+		}
+
+		end := tagEnd(s)
+		tag := s[:end]
+		s = s[end:]
+		writeTag(&out, tag)
+		name := tagName(tag)
+		if tag[1] == '/' || strings.HasSuffix(tag, "/>") {
+			continue
+		}
+
+		switch {
+		case name == "style" || name == "script":
+			close := closingTag(s, name)
+			content := s[:close]
+			s = s[close:]
+			switch {
+			case name == "style":
+				out.Write(CSS([]byte(content)))
+			case isJavaScript(tag):
+				out.Write(JS([]byte(content)))
+			default:
+				out.WriteString(content)
+			}
+		case isVerbatim(name):
+			close := closingTag(s, name)
+			out.WriteString(s[:close])
+			s = s[close:]
+		}
+	}
+	return out.Bytes()
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isVerbatim(name string) bool {
+	for _, element := range verbatim {
+		if name == element {
+			return true
+		}
+	}
+	return false
+}
+
+// tagEnd returns the length of the tag s starts with, up to its closing >
+// outside of quoted attribute values.
+func tagEnd(s string) int {
+	quote := byte(0)
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// tagName returns the lower case name of a tag like <a href> or </a>.
+func tagName(tag string) string {
+	name := strings.TrimPrefix(tag[1:], "/")
+	end := strings.IndexFunc(name, func(r rune) bool {
+		return r == '>' || r == '/' || r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+	})
+	if end >= 0 {
+		name = name[:end]
+	}
+	return strings.ToLower(name)
+}
+
+// closingTag returns the index of the closing tag of the element name in
+// s, the end of s if it has none.
+func closingTag(s, name string) int {
+	lower := strings.ToLower(s)
+	for offset := 0; ; {
+		i := strings.Index(lower[offset:], "</"+name)
+		if i < 0 {
+			return len(s)
+		}
+		i += offset
+		after := i + 2 + len(name)
+		if after == len(s) || s[after] == '>' || isSpace(s[after]) {
+			return i
+		}
+		offset = after
+	}
+}
+
+// isJavaScript reports whether a <script> tag holds JavaScript, which it
+// does without a type or with a JavaScript one.
+func isJavaScript(tag string) bool {
+	lower := strings.ToLower(tag)
+	i := strings.Index(lower, "type=")
+	if i < 0 || !isSpace(lower[i-1]) {
+		return true
+	}
+	value := strings.Trim(strings.Fields(lower[i+len("type="):] + " ")[0], "\"'>/")
+	return value == "" || value == "module" || value == "text/javascript" || value == "application/javascript"
+}
+
+// writeTag writes a tag with the whitespace between its attributes
+// collapsed, keeping quoted values as they are.
+func writeTag(out *bytes.Buffer, tag string) {
+	quote := byte(0)
+	space := false
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		if quote == 0 && isSpace(c) {
+			space = true
+			continue
+		}
+		if space && c != '>' {
+			out.WriteByte(' ')
+		}
+		space = false
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		}
+		out.WriteByte(c)
+	}
+}
+
+// writeText writes text with every run of whitespace collapsed to a
+// newline when it holds one and to a space otherwise.
+func writeText(out *bytes.Buffer, text string) {
+	for i := 0; i < len(text); {
+		if !isSpace(text[i]) {
+			out.WriteByte(text[i])
+			i++
+			continue
+		}
+		newline := false
+		for ; i < len(text) && isSpace(text[i]); i++ {
+			newline = newline || text[i] == '\n'
+		}
+		if newline {
+			out.WriteByte('\n')
+		} else {
+			out.WriteByte(' ')
+		}
+	}
+}
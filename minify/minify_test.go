@@ -0,0 +1,96 @@
+package minify
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"whitespace", "<p>\n  A   b\t c\n</p>", "<p>\nA b c\n</p>"},
+		{"comments", "<p>a<!-- note -->b</p>", "<p>ab</p>"},
+		{"conditional comments", "<!--[if IE]><p>IE</p><![endif]-->", "<!--[if IE]><p>IE</p><![endif]-->"},
+		{"tag attributes", "<a  href=\"/a  b\"\n   class='x'  >a</a>", "<a href=\"/a  b\" class='x'>a</a>"},
+		{"pre", "<pre>\n  a  <!-- b -->\n\n  c\n</pre>  <p> d </p>", "<pre>\n  a  <!-- b -->\n\n  c\n</pre> <p> d </p>"},
+		{"nested pre and code", "<pre><code class=\"go\">if a {\n\treturn\n}</code></pre>", "<pre><code class=\"go\">if a {\n\treturn\n}</code></pre>"},
+		{"textarea", "<textarea name=\"t\">  a\n\n  b  </textarea>", "<textarea name=\"t\">  a\n\n  b  </textarea>"},
+		{"upper case closing tag", "<PRE>  a  </PRE>  b", "<PRE>  a  </PRE> b"},
+		{"prefix of a verbatim tag", "<pre>a</prefix>  b</pre>", "<pre>a</prefix>  b</pre>"},
+		{"unclosed pre", "<pre>  a  ", "<pre>  a  "},
+		{"style", "<style>\n  a { color : red ; }\n</style>", "<style>a{color :red}</style>"},
+		{"script", "<script>\n  let a = 1 // one\n</script>", "<script>let a=1</script>"},
+		{"script of another type", "<script type=\"text/template\">  a  b  </script>", "<script type=\"text/template\">  a  b  </script>"},
+		{"less than in text", "<p>a < b</p>", "<p>a < b</p>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := string(HTML([]byte(test.src))); got != test.want {
+				t.Errorf("HTML(%q) = %q, want %q", test.src, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCSS(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"a {\n  color: red;\n  margin: 0 auto;\n}\n", "a{color:red;margin:0 auto}"},
+		{"/* note */ a { }", "a{}"},
+		{"/*! license */\na{}", "/*! license */ a{}"},
+		{"a :hover { }", "a :hover{}"},
+		{"a > b , c { }", "a>b,c{}"},
+		{`a::after { content: "  ;  " }`, `a::after{content:"  ;  "}`},
+		{"a { color: red !important; }", "a{color:red!important}"},
+		{"@media (max-width: 600px) { a { b: c; } }", "@media (max-width:600px){a{b:c}}"},
+	}
+	for _, test := range tests {
+		if got := string(CSS([]byte(test.src))); got != test.want {
+			t.Errorf("CSS(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestJS(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"let a = 1;\nlet b = 2;\n", "let a=1;let b=2;"},
+		{"let a = 1\nlet b = 2\n", "let a=1\nlet b=2"},
+		{"a = b // note\n/* more */ c()", "a=b\nc()"},
+		{"/*! license */\na()", "/*! license */\na()"},
+		{"a + +b; c - -d", "a+ +b;c- -d"},
+		{"return  a", "return a"},
+		{`s = "a  //  b"`, `s="a  //  b"`},
+		{"s = `a\n  ${b}`", "s=`a\n  ${b}`"},
+		{"r = /a  [/]  b/g.test(s)", "r=/a  [/]  b/g.test(s)"},
+		{"x = a / b / c", "x=a/b/c"},
+		{"if (a) {\n  b()\n}\n", "if(a){b()}"},
+		{"1 .toString()", "1 .toString()"},
+	}
+	for _, test := range tests {
+		if got := string(JS([]byte(test.src))); got != test.want {
+			t.Errorf("JS(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	var nilReport *Report
+	nilReport.Add(10, 5)
+	if got := nilReport.String(); got != "nothing minified" {
+		t.Errorf("nil Report = %q", got)
+	}
+	report := &Report{}
+	if got := report.String(); got != "nothing minified" {
+		t.Errorf("empty Report = %q", got)
+	}
+	report.Add(2048, 1024)
+	report.Add(2048, 1024)
+	if got, want := report.String(), "minified 2 files from 4.0 KiB to 2.0 KiB, saved 2.0 KiB (50.0%)"; got != want {
+		t.Errorf("Report = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,524 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mr-destructive/mr-destructive.github.io/markdown"
+	"github.com/mr-destructive/mr-destructive.github.io/oembed"
+)
+
+// ConfigError is a problem with the config file, located at Line and
+// Column of File.
+type ConfigError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// LoadConfig reads the config file at path and validates it, see
+// ParseConfig.
+func LoadConfig(path string) (SSG_CONFIG, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SSG_CONFIG{}, err
+	}
+	return ParseConfig(path, data)
+}
+
+// PluginNames are the names the plugins list may hold, set by the program
+// from its plugin registry. When it is empty the names are not checked.
+var PluginNames []string
+
+// ParseConfig decodes the config file read from file. Unknown keys and
+// values of the wrong type are reported, then the rest of the config is
+// decoded and checked: the posts, templates and static dirs must exist,
+// every template it names must be in the templates dir, every theme
+// colour must be a CSS colour, every taxonomy must have a key and a
+// {term} in its path, every oEmbed endpoint must be for a known provider
+// and every plugin must be one of PluginNames. All the
+// problems found are returned together, each a *ConfigError; only a
+// syntax error stops the checks.
+func ParseConfig(file string, data []byte) (SSG_CONFIG, error) {
+	var config SSG_CONFIG
+	checker := &configChecker{
+		file:      file,
+		data:      data,
+		decoder:   json.NewDecoder(bytes.NewReader(data)),
+		positions: make(map[string]int64),
+		invalid:   make(map[string]bool),
+	}
+	err := checker.walk("", reflect.TypeOf(config))
+	if err != nil {
+		checker.errs = append(checker.errs, checker.syntaxError(err))
+		return config, errors.Join(checker.errs...)
+	}
+	if offset := checker.next(); offset < int64(len(data)) {
+		checker.errs = append(checker.errs, checker.errorAt(offset, "unexpected data after the config object"))
+		return config, errors.Join(checker.errs...)
+	}
+	config.Markdown = markdown.DefaultConfig
+	// unknown keys are skipped and values of the wrong type, already
+	// reported, are left unset
+	err = json.Unmarshal(data, &config)
+	var typeErr *json.UnmarshalTypeError
+	if err != nil && !(errors.As(err, &typeErr) && len(checker.invalid) > 0) {
+		checker.errs = append(checker.errs, checker.syntaxError(err))
+		return config, errors.Join(checker.errs...)
+	}
+	checker.checkConfig(config)
+	return config, errors.Join(checker.errs...)
+}
+
+type configChecker struct {
+	file    string
+	data    []byte
+	decoder *json.Decoder
+	errs    []error
+	// positions holds the offset of every value walked, by path
+	positions map[string]int64
+	// invalid holds the paths of the values of the wrong type, which are
+	// not checked further
+	invalid map[string]bool
+}
+
+var (
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	rawMessageType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// walk reads the next value from the decoder, reporting keys that t has
+// no field for and values that cannot be decoded into t. Only errors that
+// stop the walk, like syntax errors, are returned.
+func (c *configChecker) walk(path string, t reflect.Type) error {
+	offset := c.next()
+	c.positions[path] = offset
+	if t == rawMessageType {
+		return c.skip()
+	}
+	token, err := c.decoder.Token()
+	if err != nil {
+		return err
+	}
+	delim, isDelim := token.(json.Delim)
+	custom := reflect.PointerTo(t).Implements(unmarshalerType)
+	switch {
+	case custom && !(isDelim && delim == '{' && t.Kind() == reflect.Struct):
+		// decoded by its own UnmarshalJSON, which reports its own errors
+		return c.skipRest(token)
+	case t.Kind() == reflect.Struct && isDelim && delim == '{':
+		fields := jsonFields(t)
+		for c.decoder.More() {
+			keyOffset := c.next()
+			key, err := c.key()
+			if err != nil {
+				return err
+			}
+			field, ok := fields[key]
+			if !ok {
+				c.errs = append(c.errs, c.errorAt(keyOffset, "unknown key %q in %s%s", key, describePath(path), suggest(key, sortedKeys(fields))))
+				if err := c.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := c.walk(joinPath(path, key), field.Type); err != nil {
+				return err
+			}
+		}
+		_, err = c.decoder.Token()
+		return err
+	case t.Kind() == reflect.Map && isDelim && delim == '{':
+		for c.decoder.More() {
+			key, err := c.key()
+			if err != nil {
+				return err
+			}
+			if err := c.walk(joinPath(path, key), t.Elem()); err != nil {
+				return err
+			}
+		}
+		_, err = c.decoder.Token()
+		return err
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && isDelim && delim == '[':
+		for i := 0; c.decoder.More(); i++ {
+			if err := c.walk(fmt.Sprintf("%s[%d]", path, i), t.Elem()); err != nil {
+				return err
+			}
+		}
+		_, err = c.decoder.Token()
+		return err
+	}
+	if token != nil && t.Kind() != reflect.Interface && kindOf(token) != jsonKind(t) {
+		c.errs = append(c.errs, c.errorAt(offset, "%s must be %s, not %s", describePath(path), jsonKind(t), kindOf(token)))
+		c.invalid[path] = true
+	}
+	return c.skipRest(token)
+}
+
+func (c *configChecker) key() (string, error) {
+	token, err := c.decoder.Token()
+	if err != nil {
+		return "", err
+	}
+	return token.(string), nil
+}
+
+// skip reads the next value whole.
+func (c *configChecker) skip() error {
+	token, err := c.decoder.Token()
+	if err != nil {
+		return err
+	}
+	return c.skipRest(token)
+}
+
+// skipRest reads the rest of the value token starts.
+func (c *configChecker) skipRest(token json.Token) error {
+	if delim, ok := token.(json.Delim); !ok || delim == '}' || delim == ']' {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		token, err := c.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// next returns the offset of the next token, past the whitespace and
+// separators the decoder has not read yet.
+func (c *configChecker) next() int64 {
+	offset := c.decoder.InputOffset()
+	for offset < int64(len(c.data)) && strings.ContainsRune(" \t\r\n,:", rune(c.data[offset])) {
+		offset++
+	}
+	return offset
+}
+
+func (c *configChecker) errorAt(offset int64, format string, args ...any) error {
+	offset = min(offset, int64(len(c.data)))
+	before := c.data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:]) + 1
+	return &ConfigError{File: c.file, Line: line, Column: column, Err: fmt.Errorf(format, args...)}
+}
+
+// errorAtPath locates an error at the value of path, or the closest
+// parent of it present in the file.
+func (c *configChecker) errorAtPath(path, format string, args ...any) error {
+	for {
+		if offset, ok := c.positions[path]; ok {
+			return c.errorAt(offset, format, args...)
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			return c.errorAt(c.positions[""], format, args...)
+		}
+		path = path[:i]
+	}
+}
+
+// report adds the error located at the value of path, unless the value,
+// or one containing it, is of the wrong type.
+func (c *configChecker) report(path, format string, args ...any) {
+	for p := path; ; {
+		if c.invalid[p] {
+			return
+		}
+		i := strings.LastIndexAny(p, ".[")
+		if i < 0 {
+			break
+		}
+		p = p[:i]
+	}
+	c.errs = append(c.errs, c.errorAtPath(path, format, args...))
+}
+
+func (c *configChecker) syntaxError(err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// the offset is past the byte in error, unless the input ended
+		offset := syntaxErr.Offset
+		if offset > 0 && offset < int64(len(c.data)) {
+			offset--
+		}
+		return c.errorAt(offset, "%v", err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return c.errorAt(typeErr.Offset, "%v", err)
+	}
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return c.errorAt(int64(len(c.data)), "unexpected end of file")
+	}
+	return &ConfigError{File: c.file, Line: 1, Column: 1, Err: err}
+}
+
+func (c *configChecker) checkConfig(config SSG_CONFIG) {
+	blog := config.Blog
+	dirs := []struct{ key, dir string }{
+		{"posts_dir", blog.PostsDir},
+		{"templates_dir", blog.TemplatesDir},
+		{"static_dir", blog.StaticDir},
+	}
+	for _, d := range dirs {
+		path := "blog." + d.key
+		if d.dir == "" {
+			c.report(path, "%s is not set", path)
+			continue
+		}
+		info, err := os.Stat(d.dir)
+		if err != nil {
+			c.report(path, "%s: directory %s does not exist", path, d.dir)
+		} else if !info.IsDir() {
+			c.report(path, "%s: %s is not a directory", path, d.dir)
+		}
+	}
+
+	checkTemplate := func(path, name string) {
+		if name == "" || blog.TemplatesDir == "" {
+			return
+		}
+		if _, err := os.Stat(filepath.Join(blog.TemplatesDir, name)); err != nil {
+			c.report(path, "%s: template %s is not in %s", path, name, blog.TemplatesDir)
+		}
+	}
+	checkTemplate("blog.default_feed_template", blog.DefaultFeedTemplate)
+	checkTemplate("blog.default_post_template", blog.DefaultPostTemplate)
+	for _, pageType := range sortedKeys(blog.PagesConfig) {
+		page := blog.PagesConfig[pageType]
+		checkTemplate("blog.pages."+pageType+".template", page.TemplatePath)
+		checkTemplate("blog.pages."+pageType+".feed_template", page.FeedTemplatePath)
+		if page.PageSize < 0 {
+			c.report("blog.pages."+pageType+".page_size", "blog.pages.%s.page_size is negative", pageType)
+		}
+	}
+
+	for _, name := range sortedKeys(blog.Themes) {
+		c.checkColors("blog.themes."+name, reflect.ValueOf(blog.Themes[name]))
+	}
+
+	for _, name := range sortedKeys(config.Taxonomies) {
+		taxonomy := config.Taxonomies[name]
+		path := "taxonomies." + name
+		if taxonomy.Key == "" {
+			c.report(path, "%s.key is not set", path)
+		}
+		if !strings.Contains(taxonomy.Path, "{term}") {
+			c.report(path+".path", "%s.path: %q has no {term}", path, taxonomy.Path)
+		}
+		if taxonomy.IndexPath != "" && taxonomy.IndexTemplate == "" {
+			c.report(path+".index_path", "%s.index_template is not set for the index page", path)
+		}
+		if taxonomy.PageSize < 0 {
+			c.report(path+".page_size", "%s.page_size is negative", path)
+		}
+		checkTemplate(path+".template", taxonomy.Template)
+		checkTemplate(path+".index_template", taxonomy.IndexTemplate)
+		if !slices.Contains([]string{"", "date", "date_asc", "title"}, taxonomy.Sort) {
+			c.report(path+".sort", "%s.sort: %q is not date, date_asc or title", path, taxonomy.Sort)
+		}
+		if !slices.Contains([]string{"", "name", "name_desc", "count"}, taxonomy.TermSort) {
+			c.report(path+".term_sort", "%s.term_sort: %q is not name, name_desc or count", path, taxonomy.TermSort)
+		}
+	}
+
+	providers := []string{}
+	for _, provider := range oembed.Providers() {
+		providers = append(providers, provider.Name)
+	}
+	for _, name := range sortedKeys(config.Oembed.Endpoints) {
+		path := "oembed.endpoints." + name
+		if !slices.Contains(providers, name) {
+			c.report(path, "oembed.endpoints: unknown provider %q%s", name, suggest(name, providers))
+		}
+	}
+
+	for i, plugin := range config.Plugins {
+		path := fmt.Sprintf("plugins[%d]", i)
+		if len(PluginNames) > 0 && !slices.Contains(PluginNames, plugin.Name) {
+			c.report(path, "%s: unknown plugin %q%s", path, plugin.Name, suggest(plugin.Name, PluginNames))
+		}
+	}
+}
+
+// checkColors checks every field of v tagged format:"color".
+func (c *configChecker) checkColors(path string, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		fieldPath := joinPath(path, name)
+		if field.Type.Kind() == reflect.Struct {
+			c.checkColors(fieldPath, v.Field(i))
+			continue
+		}
+		if field.Tag.Get("format") != "color" {
+			continue
+		}
+		if color := v.Field(i).String(); color != "" && !IsColor(color) {
+			c.report(fieldPath, "%s: %q is not a CSS colour", fieldPath, color)
+		}
+	}
+}
+
+var (
+	hexColor      = regexp.MustCompile(`^#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+	functionColor = regexp.MustCompile(`^(rgba?|hsla?|hwb|lab|lch|oklab|oklch|color|color-mix|var)\([^()]*(\([^()]*\)[^()]*)*\)$`)
+)
+
+// IsColor reports whether value is a CSS colour: a hex colour, a colour
+// function like rgb(), a named colour or a custom property.
+func IsColor(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	return hexColor.MatchString(value) || functionColor.MatchString(value) || namedColors[value]
+}
+
+var namedColors = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`transparent currentcolor
+		aliceblue antiquewhite aqua aquamarine azure beige bisque black
+		blanchedalmond blue blueviolet brown burlywood cadetblue chartreuse
+		chocolate coral cornflowerblue cornsilk crimson cyan darkblue
+		darkcyan darkgoldenrod darkgray darkgreen darkgrey darkkhaki
+		darkmagenta darkolivegreen darkorange darkorchid darkred darksalmon
+		darkseagreen darkslateblue darkslategray darkslategrey darkturquoise
+		darkviolet deeppink deepskyblue dimgray dimgrey dodgerblue firebrick
+		floralwhite forestgreen fuchsia gainsboro ghostwhite gold goldenrod
+		gray green greenyellow grey honeydew hotpink indianred indigo ivory
+		khaki lavender lavenderblush lawngreen lemonchiffon lightblue
+		lightcoral lightcyan lightgoldenrodyellow lightgray lightgreen
+		lightgrey lightpink lightsalmon lightseagreen lightskyblue
+		lightslategray lightslategrey lightsteelblue lightyellow lime
+		limegreen linen magenta maroon mediumaquamarine mediumblue
+		mediumorchid mediumpurple mediumseagreen mediumslateblue
+		mediumspringgreen mediumturquoise mediumvioletred midnightblue
+		mintcream mistyrose moccasin navajowhite navy oldlace olive olivedrab
+		orange orangered orchid palegoldenrod palegreen paleturquoise
+		palevioletred papayawhip peachpuff peru pink plum powderblue purple
+		rebeccapurple red rosybrown royalblue saddlebrown salmon sandybrown
+		seagreen seashell sienna silver skyblue slateblue slategray slategrey
+		snow springgreen steelblue tan teal thistle tomato turquoise violet
+		wheat white whitesmoke yellow yellowgreen`) {
+		namedColors[name] = true
+	}
+}
+
+// jsonFields returns the fields of struct type t by their JSON name.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// jsonKind names the kind of JSON value t is decoded from.
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// kindOf names the kind of JSON value token starts.
+func kindOf(token json.Token) string {
+	switch token.(type) {
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case json.Delim:
+		if token == json.Delim('[') {
+			return "an array"
+		}
+	}
+	return "an object"
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "the config"
+	}
+	return path
+}
+
+// suggest returns a hint naming the one of names key was probably meant to
+// be, ignoring case, dashes and underscores.
+func suggest(key string, names []string) string {
+	normalize := strings.NewReplacer("-", "", "_", "")
+	want := strings.ToLower(normalize.Replace(key))
+	for _, name := range names {
+		if strings.ToLower(normalize.Replace(name)) == want {
+			return fmt.Sprintf(", did you mean %q?", name)
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"posts", "templates", "static"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "templates", "post.html"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	names := PluginNames
+	PluginNames = []string{"readPosts", "Sitemap"}
+	t.Cleanup(func() { PluginNames = names })
+
+	dirs := `"posts_dir": "` + dir + `/posts", "templates_dir": "` + dir + `/templates", "static_dir": "` + dir + `/static"`
+	tests := []struct {
+		name string
+		data string
+		// errs are the expected errors, in order
+		errs []string
+	}{
+		{
+			name: "valid",
+			data: `{"blog": {` + dirs + `, "default_post_template": "post.html"}, "plugins": ["readPosts", {"name": "Sitemap"}]}`,
+		},
+		{
+			name: "syntax error",
+			data: "{\"blog\": {\n  \"name\": \"a\",,\n}}",
+			errs: []string{"config.json:2:15: invalid character ','"},
+		},
+		{
+			name: "unexpected end",
+			data: `{"blog": {`,
+			errs: []string{"config.json:1:11: unexpected end of JSON input"},
+		},
+		{
+			name: "data after the config",
+			data: "{\"blog\": {" + dirs + "}}\n{}",
+			errs: []string{"unexpected data after the config object"},
+		},
+		{
+			name: "unknown key with a suggestion",
+			data: "{\"blog\": {" + dirs + ",\n  \"posts-dir\": \"a\"}}",
+			errs: []string{`config.json:2:3: unknown key "posts-dir" in blog, did you mean "posts_dir"?`},
+		},
+		{
+			name: "unknown key",
+			data: "{\"blog\": {" + dirs + "},\n\"colour\": 1}",
+			errs: []string{`config.json:2:1: unknown key "colour" in the config`},
+		},
+		{
+			name: "wrong type",
+			data: "{\"blog\": {" + dirs + ",\n  \"name\": 5}}",
+			errs: []string{"config.json:2:11: blog.name must be a string, not a number"},
+		},
+		{
+			name: "checks run after structural errors",
+			data: "{\"blog\": {\n  \"posts_dir\": \"" + dir + "/missing\",\n  \"templates_dir\": 1,\n  \"title\": \"a\"}}",
+			errs: []string{
+				"config.json:3:20: blog.templates_dir must be a string, not a number",
+				`config.json:4:3: unknown key "title" in blog`,
+				"config.json:2:16: blog.posts_dir: directory " + dir + "/missing does not exist",
+				"config.json:1:10: blog.static_dir is not set",
+			},
+		},
+		{
+			name: "missing template",
+			data: "{\"blog\": {" + dirs + ",\n  \"default_post_template\": \"none.html\"}}",
+			errs: []string{"config.json:2:28: blog.default_post_template: template none.html is not in " + dir + "/templates"},
+		},
+		{
+			name: "colour",
+			data: "{\"blog\": {" + dirs + ",\n  \"themes\": {\"default\": {\"bg\": \"nope\"}}}}",
+			errs: []string{`config.json:2:32: blog.themes.default.bg: "nope" is not a CSS colour`},
+		},
+		{
+			name: "taxonomy",
+			data: "{\"blog\": {" + dirs + "},\n\"taxonomies\": {\"tags\": {\"key\": \"tags\", \"path\": \"tags/\", \"sort\": \"size\"}}}",
+			errs: []string{
+				`config.json:2:48: taxonomies.tags.path: "tags/" has no {term}`,
+				`taxonomies.tags.sort: "size" is not date, date_asc or title`,
+			},
+		},
+		{
+			name: "oEmbed endpoints",
+			data: "{\"blog\": {" + dirs + "},\n\"oembed\": {\"endpoints\": {\"youtube\": \"http://localhost\",\n  \"YouTube\": \"a\", \"flickr\": \"b\"}}}",
+			errs: []string{
+				`config.json:3:14: oembed.endpoints: unknown provider "YouTube", did you mean "youtube"?`,
+				`config.json:3:29: oembed.endpoints: unknown provider "flickr"`,
+			},
+		},
+		{
+			name: "unknown plugins",
+			data: "{\"blog\": {" + dirs + "},\n\"plugins\": [\"readPosts\",\n  \"sitemap\",\n  {\"name\": \"Comments\"}]}",
+			errs: []string{
+				`config.json:3:3: plugins[1]: unknown plugin "sitemap", did you mean "Sitemap"?`,
+				`config.json:4:3: plugins[2]: unknown plugin "Comments"`,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseConfig("config.json", []byte(test.data))
+			if len(test.errs) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("ParseConfig() error = %v, want a *ConfigError", err)
+			}
+			got := strings.Split(err.Error(), "\n")
+			if len(got) != len(test.errs) {
+				t.Fatalf("ParseConfig() errors =\n%s\nwant %d errors", err, len(test.errs))
+			}
+			for i, want := range test.errs {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("ParseConfig() error %d = %s, want %s", i, got[i], want)
+				}
+			}
+		})
+	}
+}
@@ -1,46 +1,100 @@
 package models
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"html/template"
 	"io/fs"
+
+	"github.com/mr-destructive/mr-destructive.github.io/assets"
+	"github.com/mr-destructive/mr-destructive.github.io/cache"
+	"github.com/mr-destructive/mr-destructive.github.io/markdown"
+	"github.com/mr-destructive/mr-destructive.github.io/minify"
+	"github.com/mr-destructive/mr-destructive.github.io/oembed"
 )
 
 const SSG_CONFIG_FILE_NAME string = "ssg.json"
+const SSG_CACHE_DIR string = ".ssg-cache"
+const SSG_EMBED_DIR string = ".ssg-embeds"
 
 type Author struct {
-	Name   string `json:"name"`
-	Email  string `json:"email"`
-	Github string `json:"github"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Github   string `json:"github"`
 }
 
 type PageConfig struct {
 	TemplatePath     string `json:"template"`
 	FeedTemplatePath string `json:"feed_template"`
 	Emoji            string `json:"emoji"`
+	// PageSize is the number of posts on a page of the feed, which is a
+	// single page if it is zero
+	PageSize int `json:"page_size"`
+}
+
+// Taxonomy groups the posts by the terms of a front matter key, with a
+// page listing the posts of each term and an index page of the terms.
+type Taxonomy struct {
+	// Key is the front matter key holding the terms: tags, type, status,
+	// year (of the date) or any other key of the front matter
+	Key string `json:"key"`
+	// Path is the URL of the page of a term, where {term} is replaced by it
+	Path string `json:"path"`
+	// IndexPath is the URL of the index page, there is none if it is empty
+	IndexPath string `json:"index_path"`
+	// Template renders the page of a term, blog.default_feed_template if
+	// it is empty
+	Template string `json:"template"`
+	// IndexTemplate renders the index page
+	IndexTemplate string `json:"index_template"`
+	// Sort orders the posts of a term: date (newest first, the default),
+	// date_asc or title
+	Sort string `json:"sort"`
+	// TermSort orders the terms of the index page: name (the default),
+	// name_desc or count (most posts first)
+	TermSort string `json:"term_sort"`
+	// Slugify makes a slug of the terms in the URLs, "Django Basics" is
+	// django-basics; a term which cannot be a directory name is always
+	// made a slug
+	Slugify bool `json:"slugify"`
+	// PageSize is the number of posts on a page of a term, which is a
+	// single page if it is zero
+	PageSize int `json:"page_size"`
+	// OrderKey makes the terms series, whose posts are parts ordered by
+	// the number under this front matter key and then by date, and link
+	// to each other
+	OrderKey string `json:"order_key"`
 }
 
+// Theme is a set of colours, every field is a CSS colour.
 type Theme struct {
-	Bg            string `json:"bg"`
-	Text          string `json:"text"`
-	SecondaryText string `json:"secondary-text"`
+	// ColorScheme is light or dark, guessed from Bg when empty. Readers
+	// whose prefers-color-scheme differs from the one of the default theme
+	// get the first theme with theirs.
+	ColorScheme   string `json:"color-scheme"`
+	Bg            string `json:"bg" format:"color"`
+	Text          string `json:"text" format:"color"`
+	SecondaryText string `json:"secondary-text" format:"color"`
 	Link          struct {
-		Normal string `json:"normal"`
-		Hover  string `json:"hover"`
-		Active string `json:"active"`
+		Normal string `json:"normal" format:"color"`
+		Hover  string `json:"hover" format:"color"`
+		Active string `json:"active" format:"color"`
 	} `json:"link"`
-	Quotes     string `json:"quotes"`
+	Quotes     string `json:"quotes" format:"color"`
 	CodeBlocks struct {
-		Bg     string `json:"bg"`
-		Border string `json:"border"`
+		Bg     string `json:"bg" format:"color"`
+		Border string `json:"border" format:"color"`
 	} `json:"codeblocks"`
 	Code struct {
-		Text     string `json:"text"`
-		Comment  string `json:"comment"`
-		Keyword  string `json:"keyword"`
-		String   string `json:"string"`
-		Number   string `json:"number"`
-		Variable string `json:"variable"`
-		Function string `json:"function"`
+		Text     string `json:"text" format:"color"`
+		Comment  string `json:"comment" format:"color"`
+		Keyword  string `json:"keyword" format:"color"`
+		String   string `json:"string" format:"color"`
+		Number   string `json:"number" format:"color"`
+		Variable string `json:"variable" format:"color"`
+		Function string `json:"function" format:"color"`
 	} `json:"code"`
 }
 
@@ -62,11 +116,54 @@ type BlogConfig struct {
 	CloudFunction       map[string]string     `json:"cloud_function"`
 }
 
+type BuildConfig struct {
+	Workers int `json:"workers"`
+}
+
+// PluginConfig is an entry of the plugins list, either the plugin name
+// alone or an object with the name and the options of the plugin.
+type PluginConfig struct {
+	Name    string          `json:"name"`
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+func (p *PluginConfig) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*p = PluginConfig{Name: name}
+		return nil
+	}
+	type plain PluginConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plain)(p))
+}
+
+func (p PluginConfig) MarshalJSON() ([]byte, error) {
+	if len(p.Options) == 0 {
+		return json.Marshal(p.Name)
+	}
+	type plain PluginConfig
+	return json.Marshal(plain(p))
+}
+
 type SSG_CONFIG struct {
-	Blog      BlogConfig `json:"blog"`
-	Authors   []Author   `json:"authors"`
-	Plugins   []string   `json:"plugins"`
-	AdminMode bool
+	// Schema points editors at the JSON Schema of the file
+	Schema   string          `json:"$schema,omitempty"`
+	Blog     BlogConfig      `json:"blog"`
+	Authors  []Author        `json:"authors"`
+	Plugins  []PluginConfig  `json:"plugins"`
+	Build    BuildConfig     `json:"build"`
+	Markdown markdown.Config `json:"markdown"`
+	Oembed   oembed.Config   `json:"oembed"`
+	Assets   assets.Config   `json:"assets"`
+	Minify   minify.Config   `json:"minify"`
+	// Taxonomies are the ways posts are grouped, by taxonomy name
+	Taxonomies map[string]Taxonomy `json:"taxonomies"`
+	AdminMode  bool                `json:"-"`
+	// Dev is set when building for the dev server, which skips the work
+	// only the deployed site needs, like minifying
+	Dev bool `json:"-"`
 }
 
 var config *SSG_CONFIG
@@ -101,18 +198,23 @@ type Post struct {
 	Frontmatter FrontMatter
 	Content     template.HTML
 	Markdown    string
+	SourcePath  string
+	SourceHash  string
+	// Bundle is the directory of the page bundle whose index.md the post
+	// is, empty for a post in a single file
+	Bundle string
 }
 
 type DBPost struct {
-    ID          int    `json:"id"`
-    Title       string `json:"title"`
-    Slug        string `json:"slug"`
-    Body        string `json:"body"`
-    Metadata    string `json:"metadata"`
-    Deleted     bool   `json:"deleted"`
-    CreatedAt   string `json:"created_at"`
-    UpdatedAt   string `json:"updated_at"`
-    AuthorID    int    `json:"author_id"`
+	ID        int    `json:"id"`
+	Title     string `json:"title"`
+	Slug      string `json:"slug"`
+	Body      string `json:"body"`
+	Metadata  string `json:"metadata"`
+	Deleted   bool   `json:"deleted"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+	AuthorID  int    `json:"author_id"`
 }
 
 type Feed struct {
@@ -128,6 +230,25 @@ type SSG struct {
 	FeedPosts  []Feed
 	TemplateFS *template.Template
 	FS         fs.FS
+	Cache      *cache.BuildCache
+	// Markdown converts the posts, built once from Config.Markdown
+	Markdown *markdown.Renderer
+	// Series holds the series of every post, by source path
+	Series map[string][]Series
+	// Related holds the related posts of every post, by source path
+	Related map[string][]Post
+	// FeedLinks holds the feeds of the site and of its feed pages, by the
+	// slug of the page, the site root for the site
+	FeedLinks map[string][]FeedLink
+	// Manifest lists the pages written by the build, for the sitemap
+	Manifest *Manifest
+	// Assets are the files of the static dir, scanned by copyStaticFiles
+	Assets *assets.Pipeline
+	// Minified counts the bytes minifying saved over the build
+	Minified *minify.Report
+	// hashes of the templates dir and the config, part of every cache key
+	TemplateHash string
+	ConfigHash   string
 }
 
 type ThemeCombo struct {
@@ -140,4 +261,81 @@ type TemplateContext struct {
 	Post      Post
 	FeedPosts []Feed
 	FeedInfo  Feed
+	// Paginator is set on the pages of feeds, whose FeedInfo only holds
+	// the posts of the page
+	Paginator Paginator
+	// Series are the series the post is part of
+	Series []Series
+	// Related are the posts related to the post, the most related first
+	Related []Post
+	// Feeds are the feeds of the page, for <link rel="alternate">
+	Feeds []FeedLink
+}
+
+// FeedLink is a feed of a page in one format.
+type FeedLink struct {
+	Title string
+	// Format is the name of the format, RSS, Atom or JSON Feed
+	Format string
+	// Type is the media type of the format, like application/rss+xml
+	Type string
+	// URL is the absolute URL of the feed
+	URL string
+}
+
+// Series is the place of a post among the parts of a series, a term of a
+// taxonomy with an order key.
+type Series struct {
+	Taxonomy string
+	Name     string
+	// URL is the URL of the page of the series, like Feed.Slug
+	URL string
+	// Part is the number of the post in the series, from 1
+	Part  int
+	Total int
+	// Prev and Next are the parts around the post, nil at either end
+	Prev  *Post
+	Next  *Post
+	Posts []Post
+}
+
+// Paginator is the place of a page among the pages of a feed. The URLs
+// are relative to the site root, like Feed.Slug, and empty when there is
+// no such page.
+type Paginator struct {
+	// Page is the number of the page, from 1
+	Page       int
+	TotalPages int
+	TotalPosts int
+	PageSize   int
+	// BaseURL is the URL of the first page, the URL of the feed
+	BaseURL string
+	PrevURL string
+	NextURL string
+}
+
+// PageLink is a link to a page of a feed.
+type PageLink struct {
+	Number  int
+	URL     string
+	Current bool
+}
+
+// URL returns the URL of page number, the first page keeps the URL of
+// the feed and the others are at <feed>/page/<number>.
+func (p Paginator) URL(number int) string {
+	if number <= 1 {
+		return p.BaseURL
+	}
+	return fmt.Sprintf("%s/page/%d", p.BaseURL, number)
+}
+
+// Window returns the links to the pages at most size pages away from the
+// current one, like {{ range .Paginator.Window 2 }}.
+func (p Paginator) Window(size int) []PageLink {
+	links := []PageLink{}
+	for number := max(1, p.Page-size); number <= min(p.TotalPages, p.Page+size); number++ {
+		links = append(links, PageLink{Number: number, URL: p.URL(number), Current: number == p.Page})
+	}
+	return links
 }
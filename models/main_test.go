@@ -0,0 +1,54 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPaginatorWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		page       int
+		totalPages int
+		size       int
+		want       string
+	}{
+		{"single page", 1, 1, 2, "[1]"},
+		{"first page", 1, 10, 2, "[1] 2 3"},
+		{"middle page", 5, 10, 2, "3 4 [5] 6 7"},
+		{"last page", 10, 10, 2, "8 9 [10]"},
+		{"window wider than the pages", 2, 3, 5, "1 [2] 3"},
+		{"no window", 4, 10, 0, "[4]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			paginator := Paginator{Page: test.page, TotalPages: test.totalPages, BaseURL: "tags/go"}
+			got := ""
+			for i, link := range paginator.Window(test.size) {
+				if i > 0 {
+					got += " "
+				}
+				if link.URL != paginator.URL(link.Number) {
+					t.Errorf("link to page %d has URL %q, want %q", link.Number, link.URL, paginator.URL(link.Number))
+				}
+				if link.Current {
+					got += fmt.Sprintf("[%d]", link.Number)
+				} else {
+					got += fmt.Sprint(link.Number)
+				}
+			}
+			if got != test.want {
+				t.Errorf("Window(%d) = %s, want %s", test.size, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPaginatorURL(t *testing.T) {
+	paginator := Paginator{BaseURL: "tags/go"}
+	for number, want := range map[int]string{0: "tags/go", 1: "tags/go", 2: "tags/go/page/2", 12: "tags/go/page/12"} {
+		if got := paginator.URL(number); got != want {
+			t.Errorf("URL(%d) = %q, want %q", number, got, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Manifest lists the HTML pages a build wrote to the output dir, fresh
+// ones included, with what the sitemap tells about them. A nil Manifest
+// records nothing, and it is safe for concurrent use like the build cache.
+type Manifest struct {
+	mu    sync.Mutex
+	pages map[string]ManifestPage
+}
+
+// ManifestPage is a page of the output dir.
+type ManifestPage struct {
+	// Output is the written file, like public/posts/slug/index.html
+	Output string
+	// LastMod is the day the content of the page last changed, empty when
+	// it is not known
+	LastMod string
+	// Images are the URLs of the images of the page, as in the front matter
+	Images []string
+}
+
+// Add records page, replacing an earlier page with the same output.
+func (m *Manifest) Add(page ManifestPage) {
+	if m == nil {
+		return
+	}
+	page.Output = filepath.Clean(page.Output)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pages == nil {
+		m.pages = make(map[string]ManifestPage)
+	}
+	m.pages[page.Output] = page
+}
+
+// Pages returns the pages written below dir, sorted by output.
+func (m *Manifest) Pages(dir string) []ManifestPage {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pages := []ManifestPage{}
+	for output, page := range m.pages {
+		rel, err := filepath.Rel(filepath.Clean(dir), output)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			pages = append(pages, page)
+		}
+	}
+	slices.SortFunc(pages, func(a, b ManifestPage) int {
+		return strings.Compare(a.Output, b.Output)
+	})
+	return pages
+}
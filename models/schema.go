@@ -0,0 +1,78 @@
+package models
+
+import (
+	"reflect"
+)
+
+// schemaer is implemented by types whose JSON form differs from their
+// fields, to describe it themselves.
+type schemaer interface {
+	JSONSchema() map[string]any
+}
+
+var schemaerType = reflect.TypeOf((*schemaer)(nil)).Elem()
+
+// ConfigSchema returns the JSON Schema of the config file, generated from
+// SSG_CONFIG so that it always matches what the build accepts.
+func ConfigSchema() map[string]any {
+	schema := typeSchema(reflect.TypeOf(SSG_CONFIG{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "ssg config"
+	return schema
+}
+
+func typeSchema(t reflect.Type) map[string]any {
+	if t.Implements(schemaerType) {
+		return reflect.Zero(t).Interface().(schemaer).JSONSchema()
+	}
+	if t == rawMessageType {
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		for name, field := range jsonFields(t) {
+			property := typeSchema(field.Type)
+			if format := field.Tag.Get("format"); format != "" {
+				property["format"] = format
+			}
+			properties[name] = property
+		}
+		return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	}
+	return map[string]any{}
+}
+
+// JSONSchema describes a plugins list entry, a plugin name or an object
+// with the name and the options of the plugin.
+func (p PluginConfig) JSONSchema() map[string]any {
+	return map[string]any{
+		"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":    map[string]any{"type": "string"},
+					"options": map[string]any{"type": "object"},
+				},
+				"required":             []string{"name"},
+				"additionalProperties": false,
+			},
+		},
+	}
+}
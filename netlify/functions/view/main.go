@@ -1,22 +1,44 @@
 package main
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
+	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/yuin/goldmark"
-	"bytes"
+	"github.com/mr-destructive/mr-destructive.github.io/markdown"
+	models "github.com/mr-destructive/mr-destructive.github.io/models"
+	"github.com/mr-destructive/mr-destructive.github.io/shortcode"
 )
 
+// renderer converts the posts the same way the static build does, with
+// the markdown section of the config and the shortcode templates when they
+// are deployed along.
+var renderer = newRenderer()
+
+func newRenderer() *markdown.Renderer {
+	config, err := markdown.LoadConfig(models.SSG_CONFIG_FILE_NAME)
+	if err != nil {
+		log.Printf("Reading the markdown config: %v", err)
+	}
+	shortcodes, err := shortcode.New(filepath.Join("templates", "shortcodes"))
+	if err != nil {
+		log.Printf("Loading the shortcodes: %v", err)
+		shortcodes, _ = shortcode.New("")
+	}
+	return markdown.New(config).WithShortcodes(shortcodes)
+}
+
 // DB represents the database connection
 type DB struct {
 	*sql.DB
@@ -108,7 +130,7 @@ func ViewHandler(req events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 	if dbPath == "" {
 		dbPath = "./data/blog.db"
 	}
-	
+
 	db, err := InitDB(dbPath)
 	if err != nil {
 		return events.APIGatewayProxyResponse{
@@ -157,7 +179,13 @@ func ViewHandler(req events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 
 	// Convert markdown to HTML
 	var buf bytes.Buffer
-	if err := goldmark.Convert([]byte(post.Body), &buf); err != nil {
+	err = renderer.Render(fmt.Sprintf("post %d", post.ID), 1, []byte(post.Body), &buf)
+	if errors.Is(err, shortcode.ErrIncomplete) {
+		// the stand-ins of the embeds that could not be fetched are shown
+		log.Println(err)
+		err = nil
+	}
+	if err != nil {
 		return events.APIGatewayProxyResponse{
 			StatusCode: http.StatusInternalServerError,
 			Headers: map[string]string{
@@ -270,4 +298,4 @@ func ViewHandler(req events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 
 func main() {
 	lambda.Start(ViewHandler)
-}
\ No newline at end of file
+}
@@ -0,0 +1,263 @@
+// Package oembed fetches the embed HTML of links to sites like Twitter or
+// YouTube from their oEmbed endpoint, and keeps every response on disk so
+// that a build can run without the network.
+package oembed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmlpkg "html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mr-destructive/mr-destructive.github.io/cache"
+)
+
+// Config is the "oembed" section of the config file.
+type Config struct {
+	// Timeout is the number of seconds a request may take, 10 if zero
+	Timeout int `json:"timeout"`
+	// Endpoints replaces the endpoint of providers, by provider name
+	Endpoints map[string]string `json:"endpoints"`
+}
+
+// Response is the part of an oEmbed response the site uses.
+type Response struct {
+	Type         string `json:"type"`
+	Title        string `json:"title,omitempty"`
+	ProviderName string `json:"provider_name,omitempty"`
+	HTML         string `json:"html"`
+}
+
+// Provider is a site whose links can be embedded.
+type Provider struct {
+	Name string
+	// Hosts are the hosts of the links of the site, without "www."
+	Hosts    []string
+	Endpoint string
+	// fetchURL returns the URL the response to link is fetched from, a
+	// query to Endpoint if nil
+	fetchURL func(endpoint string, link *url.URL) (string, error)
+	// decode reads the response, an oEmbed JSON response if nil
+	decode func(body []byte) (Response, error)
+}
+
+// Providers returns the known providers.
+func Providers() []Provider {
+	return []Provider{
+		{Name: "twitter", Hosts: []string{"twitter.com", "x.com"}, Endpoint: "https://publish.twitter.com/oembed"},
+		{Name: "youtube", Hosts: []string{"youtube.com", "youtu.be", "m.youtube.com"}, Endpoint: "https://www.youtube.com/oembed"},
+		{Name: "vimeo", Hosts: []string{"vimeo.com", "player.vimeo.com"}, Endpoint: "https://vimeo.com/api/oembed.json"},
+		{Name: "codepen", Hosts: []string{"codepen.io"}, Endpoint: "https://codepen.io/api/oembed"},
+		// gists have no oEmbed endpoint, but a JSON form with their HTML
+		{Name: "gist", Hosts: []string{"gist.github.com"}, Endpoint: "https://gist.github.com", fetchURL: gistURL, decode: decodeGist},
+	}
+}
+
+// ErrOffline is returned for a link whose response is not cached when the
+// client may not use the network.
+var ErrOffline = errors.New("not cached and offline")
+
+// ErrNoProvider is returned for a link to a site without a provider.
+var ErrNoProvider = errors.New("no oEmbed provider")
+
+// Client fetches and caches responses.
+type Client struct {
+	Providers []Provider
+	// Dir keeps a file per response, nothing is kept on disk if empty
+	Dir string
+	// Offline only uses the responses kept in Dir
+	Offline bool
+	HTTP    *http.Client
+
+	mu     sync.Mutex
+	memory map[string]Response
+}
+
+// NewClient returns a client for the known providers, with the endpoints
+// and timeout of config, keeping the responses in dir.
+func NewClient(config Config, dir string, offline bool) *Client {
+	providers := Providers()
+	for i, provider := range providers {
+		if endpoint, ok := config.Endpoints[provider.Name]; ok {
+			providers[i].Endpoint = endpoint
+		}
+	}
+	timeout := time.Duration(config.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Client{
+		Providers: providers,
+		Dir:       dir,
+		Offline:   offline,
+		HTTP:      &http.Client{Timeout: timeout},
+		memory:    make(map[string]Response),
+	}
+}
+
+// Provider returns the provider of link, if there is one.
+func (c *Client) Provider(link string) (Provider, bool) {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return Provider{}, false
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	for _, provider := range c.Providers {
+		for _, known := range provider.Hosts {
+			if host == known {
+				return provider, true
+			}
+		}
+	}
+	return Provider{}, false
+}
+
+// Fetch returns the response for link, from the cache if it holds one.
+func (c *Client) Fetch(link string) (Response, error) {
+	provider, ok := c.Provider(link)
+	if !ok {
+		return Response{}, ErrNoProvider
+	}
+	c.mu.Lock()
+	response, ok := c.memory[link]
+	c.mu.Unlock()
+	if ok {
+		return response, nil
+	}
+	response, err := c.read(link)
+	if err == nil {
+		return c.remember(link, response), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return Response{}, err
+	}
+	if c.Offline {
+		return Response{}, ErrOffline
+	}
+	response, err = c.fetch(provider, link)
+	if err != nil {
+		return Response{}, fmt.Errorf("%s: %w", provider.Name, err)
+	}
+	if err := c.write(link, response); err != nil {
+		return Response{}, err
+	}
+	return c.remember(link, response), nil
+}
+
+func (c *Client) remember(link string, response Response) Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.memory[link] = response
+	return response
+}
+
+func (c *Client) fetch(provider Provider, link string) (Response, error) {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return Response{}, err
+	}
+	fetchURL := provider.Endpoint + "?" + url.Values{"url": {link}, "format": {"json"}}.Encode()
+	if provider.fetchURL != nil {
+		fetchURL, err = provider.fetchURL(provider.Endpoint, parsed)
+		if err != nil {
+			return Response{}, err
+		}
+	}
+	resp, err := c.HTTP.Get(fetchURL)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, fmt.Errorf("GET %s: %s", fetchURL, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, err
+	}
+	if provider.decode != nil {
+		return provider.decode(body)
+	}
+	var response Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		return Response{}, err
+	}
+	if response.HTML == "" {
+		return Response{}, fmt.Errorf("GET %s: response without html", fetchURL)
+	}
+	return response, nil
+}
+
+func (c *Client) path(link string) string {
+	return filepath.Join(c.Dir, cache.Hash(link)+".json")
+}
+
+func (c *Client) read(link string) (Response, error) {
+	if c.Dir == "" {
+		return Response{}, os.ErrNotExist
+	}
+	data, err := os.ReadFile(c.path(link))
+	if err != nil {
+		return Response{}, err
+	}
+	var entry struct {
+		URL      string   `json:"url"`
+		Response Response `json:"response"`
+	}
+	err = json.Unmarshal(data, &entry)
+	return entry.Response, err
+}
+
+func (c *Client) write(link string, response Response) error {
+	if c.Dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(map[string]any{"url": link, "response": response}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path(link), data, 0660)
+}
+
+// gistURL returns the URL of the JSON form of the gist at link.
+func gistURL(endpoint string, link *url.URL) (string, error) {
+	segments := strings.Split(strings.Trim(link.Path, "/"), "/")
+	if len(segments) != 2 {
+		return "", fmt.Errorf("%s is not a gist url", link)
+	}
+	fetchURL := strings.TrimSuffix(endpoint, "/") + "/" + segments[0] + "/" + segments[1] + ".json"
+	if file := link.Query().Get("file"); file != "" {
+		fetchURL += "?" + url.Values{"file": {file}}.Encode()
+	}
+	return fetchURL, nil
+}
+
+func decodeGist(body []byte) (Response, error) {
+	var gist struct {
+		Description string `json:"description"`
+		Div         string `json:"div"`
+		Stylesheet  string `json:"stylesheet"`
+	}
+	if err := json.Unmarshal(body, &gist); err != nil {
+		return Response{}, err
+	}
+	if gist.Div == "" {
+		return Response{}, errors.New("gist response without div")
+	}
+	html := gist.Div
+	if gist.Stylesheet != "" {
+		html = fmt.Sprintf(`<link rel="stylesheet" href="%s">`, htmlpkg.EscapeString(gist.Stylesheet)) + html
+	}
+	return Response{Type: "rich", Title: gist.Description, ProviderName: "GitHub", HTML: html}, nil
+}
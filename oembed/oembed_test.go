@@ -0,0 +1,189 @@
+package oembed
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testServer is a stand-in for the providers, answering every endpoint
+// with the oEmbed response of the url asked for and gists with their JSON
+// form. It counts the requests it gets.
+func testServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/gist/"):
+			fmt.Fprintf(w, `{"description": "A gist", "div": "<div class=\"gist\">%s %s</div>", "stylesheet": "https://gist.example/a.css"}`,
+				strings.TrimPrefix(r.URL.Path, "/gist/"), r.URL.Query().Get("file"))
+		case r.URL.Path == "/error":
+			http.Error(w, "nope", http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/malformed"):
+			fmt.Fprint(w, `{"html": `)
+		case r.URL.Path == "/empty":
+			fmt.Fprint(w, `{"type": "video"}`)
+		case r.URL.Path == "/slow":
+			time.Sleep(200 * time.Millisecond)
+			fmt.Fprint(w, `{"html": "late"}`)
+		default:
+			if r.URL.Query().Get("format") != "json" {
+				t.Errorf("request %s without format=json", r.URL)
+			}
+			fmt.Fprintf(w, `{"type": "rich", "provider_name": %q, "html": "<embed %s>"}`, r.URL.Path, r.URL.Query().Get("url"))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// testClient returns a client for the known providers with their
+// endpoints on server, each at the path of its name.
+func testClient(server *httptest.Server, dir string, offline bool) *Client {
+	endpoints := map[string]string{}
+	for _, provider := range Providers() {
+		endpoints[provider.Name] = server.URL + "/" + provider.Name
+	}
+	return NewClient(Config{Endpoints: endpoints}, dir, offline)
+}
+
+func TestFetch(t *testing.T) {
+	tests := []struct {
+		link string
+		want Response
+	}{
+		{"https://twitter.com/a/status/1", Response{Type: "rich", ProviderName: "/twitter", HTML: "<embed https://twitter.com/a/status/1>"}},
+		{"https://x.com/a/status/1", Response{Type: "rich", ProviderName: "/twitter", HTML: "<embed https://x.com/a/status/1>"}},
+		{"https://www.youtube.com/watch?v=abc", Response{Type: "rich", ProviderName: "/youtube", HTML: "<embed https://www.youtube.com/watch?v=abc>"}},
+		{"https://youtu.be/abc", Response{Type: "rich", ProviderName: "/youtube", HTML: "<embed https://youtu.be/abc>"}},
+		{"https://vimeo.com/1", Response{Type: "rich", ProviderName: "/vimeo", HTML: "<embed https://vimeo.com/1>"}},
+		{"https://codepen.io/a/pen/b", Response{Type: "rich", ProviderName: "/codepen", HTML: "<embed https://codepen.io/a/pen/b>"}},
+		{"https://gist.github.com/a/b", Response{Type: "rich", Title: "A gist", ProviderName: "GitHub",
+			HTML: `<link rel="stylesheet" href="https://gist.example/a.css"><div class="gist">a/b.json </div>`}},
+		{"https://gist.github.com/a/b?file=c.go", Response{Type: "rich", Title: "A gist", ProviderName: "GitHub",
+			HTML: `<link rel="stylesheet" href="https://gist.example/a.css"><div class="gist">a/b.json c.go</div>`}},
+	}
+	server, _ := testServer(t)
+	client := testClient(server, "", false)
+	for _, test := range tests {
+		t.Run(test.link, func(t *testing.T) {
+			response, err := client.Fetch(test.link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response != test.want {
+				t.Errorf("Fetch() = %+v, want %+v", response, test.want)
+			}
+		})
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		link     string
+		want     string
+	}{
+		{"no provider", "", "https://example.com/a", ErrNoProvider.Error()},
+		{"not a gist", "", "https://gist.github.com/a", "is not a gist url"},
+		{"error status", "/error", "https://vimeo.com/1", "404 Not Found"},
+		{"malformed response", "/malformed", "https://vimeo.com/1", "unexpected end of JSON input"},
+		{"response without html", "/empty", "https://vimeo.com/1", "response without html"},
+		{"malformed gist response", "/malformed", "https://gist.github.com/a/b", "unexpected end of JSON input"},
+	}
+	server, _ := testServer(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			client := testClient(server, dir, false)
+			if test.endpoint != "" {
+				for i := range client.Providers {
+					client.Providers[i].Endpoint = server.URL + test.endpoint
+				}
+			}
+			_, err := client.Fetch(test.link)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("Fetch() error = %v, want %q", err, test.want)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+				t.Errorf("the failed response was kept on disk")
+			}
+		})
+	}
+}
+
+func TestFetchCache(t *testing.T) {
+	server, requests := testServer(t)
+	dir := t.TempDir()
+	link := "https://vimeo.com/1"
+	first, err := testClient(server, dir, false).Fetch(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a new client reads the response kept on disk
+	client := testClient(server, dir, false)
+	for range 2 {
+		response, err := client.Fetch(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response != first {
+			t.Errorf("cached response = %+v, want %+v", response, first)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("%d requests, want 1", got)
+	}
+}
+
+func TestFetchOffline(t *testing.T) {
+	server, requests := testServer(t)
+	dir := t.TempDir()
+	cached := "https://vimeo.com/1"
+	want, err := testClient(server, dir, false).Fetch(cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests.Store(0)
+
+	client := testClient(server, dir, true)
+	response, err := client.Fetch(cached)
+	if err != nil || response != want {
+		t.Errorf("Fetch(%s) = %+v, %v, want the cached response", cached, response, err)
+	}
+	if _, err := client.Fetch("https://vimeo.com/2"); !errors.Is(err, ErrOffline) {
+		t.Errorf("Fetch() of an uncached link error = %v, want ErrOffline", err)
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("%d requests offline, want 0", got)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	if timeout := NewClient(Config{}, "", false).HTTP.Timeout; timeout != 10*time.Second {
+		t.Errorf("default timeout = %v, want 10s", timeout)
+	}
+	if timeout := NewClient(Config{Timeout: 3}, "", false).HTTP.Timeout; timeout != 3*time.Second {
+		t.Errorf("timeout = %v, want 3s", timeout)
+	}
+
+	server, _ := testServer(t)
+	client := testClient(server, "", false)
+	for i := range client.Providers {
+		client.Providers[i].Endpoint = server.URL + "/slow"
+	}
+	client.HTTP.Timeout = 50 * time.Millisecond
+	_, err := client.Fetch("https://vimeo.com/1")
+	if err == nil || !strings.Contains(err.Error(), "Timeout") {
+		t.Errorf("Fetch() error = %v, want a timeout", err)
+	}
+}
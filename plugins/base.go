@@ -7,15 +7,16 @@ import (
 )
 
 type BasePlugin struct {
-	PluginName string
+	PluginName string `json:"-"`
 }
 
 func (bp *BasePlugin) Name() string {
 	return bp.PluginName
 }
 
-func (bp *BasePlugin) Execute(ssg *models.SSG) {
+func (bp *BasePlugin) Execute(ssg *models.SSG) error {
 	// write the plugin here
+	return nil
 }
 
 // uncomment this
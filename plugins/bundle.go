@@ -0,0 +1,112 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// BundleIndex is the post of a page bundle, a directory of the posts dir
+// holding a post along with the images and attachments it links to.
+const BundleIndex = "index.md"
+
+// bundleLink matches the links and images of the content, whose relative
+// targets are files of the page bundle.
+var bundleLink = regexp.MustCompile(`(\s(?:href|src)=")([^"]*)"`)
+
+// ResolveBundleLinks makes the relative links and images of a post of a
+// page bundle, and its image_url, relative to the site root instead, as
+// the post may be rendered at several paths. A relative link to a file
+// missing from the bundle is reported and left as it is.
+func ResolveBundleLinks(ssg *models.SSG, post *models.Post) error {
+	base := "/" + ssg.Config.Blog.PrefixURL + PostDir(*post) + "/"
+	var errs []error
+	resolve := func(link string) (string, bool) {
+		ref, err := url.Parse(link)
+		if err != nil || ref.Scheme != "" || ref.Host != "" || ref.Path == "" || strings.HasPrefix(ref.Path, "/") {
+			return link, true
+		}
+		rel := path.Clean(ref.Path)
+		// links out of the bundle, like ../other-post/, are not its files
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return link, true
+		}
+		if _, err := os.Stat(filepath.Join(post.Bundle, filepath.FromSlash(rel))); err != nil {
+			errs = append(errs, PageError(post.SourcePath, "", fmt.Errorf("link %q: no file %s in the page bundle", link, rel)))
+			return link, false
+		}
+		switch {
+		case rel == BundleIndex:
+			ref.Path = base
+		case strings.HasSuffix(ref.Path, "/"):
+			ref.Path = base + rel + "/"
+		default:
+			ref.Path = base + rel
+		}
+		ref.RawPath = ""
+		return ref.String(), true
+	}
+
+	content := bundleLink.ReplaceAllStringFunc(string(post.Content), func(attr string) string {
+		match := bundleLink.FindStringSubmatch(attr)
+		link, ok := resolve(html.UnescapeString(match[2]))
+		if !ok {
+			return attr
+		}
+		return match[1] + html.EscapeString(link) + `"`
+	})
+	post.Content = template.HTML(content)
+	if post.Frontmatter.ImageUrl != "" {
+		post.Frontmatter.ImageUrl, _ = resolve(post.Frontmatter.ImageUrl)
+	}
+	return errors.Join(errs...)
+}
+
+// CopyBundles copies the files of the page bundles of the rendered posts
+// next to their pages, other than their index.md.
+func CopyBundles(ssg *models.SSG) error {
+	var errs []error
+	copied := 0
+	for _, post := range ssg.Posts {
+		if post.Bundle == "" || post.Frontmatter.Status == "draft" || post.Frontmatter.Date == "" {
+			continue
+		}
+		pageDir := filepath.Join(ssg.Config.Blog.OutputDir, filepath.FromSlash(PostDir(post)))
+		err := filepath.WalkDir(post.Bundle, func(source string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return PageError(source, "", err)
+			}
+			rel, err := filepath.Rel(post.Bundle, source)
+			if err != nil {
+				return PageError(source, "", err)
+			}
+			if d.IsDir() || rel == BundleIndex {
+				return nil
+			}
+			data, err := os.ReadFile(source)
+			if err != nil {
+				return PageError(source, "", err)
+			}
+			written, err := WriteOutput(ssg, filepath.Join(pageDir, rel), data)
+			if written {
+				copied++
+			}
+			return err
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	fmt.Println("Bundle files copied:", copied)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,124 @@
+package plugins
+
+import (
+	"errors"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// bundleSite returns a site with the page bundle posts/my-post holding
+// index.md, photo.png and files/notes.txt.
+func bundleSite(t *testing.T) (*models.SSG, string) {
+	t.Helper()
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "posts", "my-post")
+	for _, name := range []string{"index.md", "photo.png", "files/notes.txt"} {
+		path := filepath.Join(bundle, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ssg := &models.SSG{}
+	ssg.Config.Blog.OutputDir = filepath.Join(dir, "public")
+	ssg.Config.Blog.PrefixURL = "blog/"
+	return ssg, bundle
+}
+
+func bundlePost(bundle, content string) models.Post {
+	post := models.Post{Content: template.HTML(content), Bundle: bundle, SourcePath: filepath.Join(bundle, BundleIndex)}
+	post.Frontmatter.Slug = "my-post"
+	post.Frontmatter.Date = "2024-01-31"
+	return post
+}
+
+func TestResolveBundleLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		err     string
+	}{
+		{"image", `<img src="photo.png">`, `<img src="/blog/posts/my-post/photo.png">`, ""},
+		{"nested file with a fragment", `<a href="./files/notes.txt#top">`, `<a href="/blog/posts/my-post/files/notes.txt#top">`, ""},
+		{"directory", `<a href="files/">`, `<a href="/blog/posts/my-post/files/">`, ""},
+		{"index.md", `<a href="index.md">`, `<a href="/blog/posts/my-post/">`, ""},
+		{"escaped link", `<a href="photo.png?a=1&amp;b=2">`, `<a href="/blog/posts/my-post/photo.png?a=1&amp;b=2">`, ""},
+		{"out of the bundle", `<a href="../other-post/">`, `<a href="../other-post/">`, ""},
+		{"absolute and remote links", `<a href="/about/"><a href="https://example.com/a.png"><a href="#top">`, `<a href="/about/"><a href="https://example.com/a.png"><a href="#top">`, ""},
+		{"missing file", `<img src="gone.png"> <img src="photo.png">`, `<img src="gone.png"> <img src="/blog/posts/my-post/photo.png">`, `link "gone.png": no file gone.png in the page bundle`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ssg, bundle := bundleSite(t)
+			post := bundlePost(bundle, test.content)
+			err := ResolveBundleLinks(ssg, &post)
+			if test.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if test.err != "" {
+				var diagnostic *Diagnostic
+				if !errors.As(err, &diagnostic) || diagnostic.Source != post.SourcePath || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("ResolveBundleLinks() error = %v, want a page error %q", err, test.err)
+				}
+			}
+			if string(post.Content) != test.want {
+				t.Errorf("content = %s, want %s", post.Content, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveBundleImageURL(t *testing.T) {
+	ssg, bundle := bundleSite(t)
+	post := bundlePost(bundle, "")
+	post.Frontmatter.ImageUrl = "photo.png"
+	if err := ResolveBundleLinks(ssg, &post); err != nil {
+		t.Fatal(err)
+	}
+	if post.Frontmatter.ImageUrl != "/blog/posts/my-post/photo.png" {
+		t.Errorf("image_url = %s", post.Frontmatter.ImageUrl)
+	}
+}
+
+func TestCopyBundles(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		date   string
+		copied bool
+	}{
+		{"published", "", "2024-01-31", true},
+		{"draft", "draft", "2024-01-31", false},
+		{"undated", "", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ssg, bundle := bundleSite(t)
+			post := bundlePost(bundle, "")
+			post.Frontmatter.Status = test.status
+			post.Frontmatter.Date = test.date
+			ssg.Posts = []models.Post{post}
+			if err := CopyBundles(ssg); err != nil {
+				t.Fatal(err)
+			}
+			page := filepath.Join(ssg.Config.Blog.OutputDir, "posts", "my-post")
+			for _, name := range []string{"photo.png", "files/notes.txt"} {
+				data, err := os.ReadFile(filepath.Join(page, filepath.FromSlash(name)))
+				if copied := err == nil && string(data) == name; copied != test.copied {
+					t.Errorf("%s copied: %v, want %v", name, copied, test.copied)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(page, BundleIndex)); err == nil {
+				t.Error("index.md was copied")
+			}
+		})
+	}
+}
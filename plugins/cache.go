@@ -0,0 +1,69 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/mr-destructive/mr-destructive.github.io/cache"
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// PostKey is the build cache key of a post page: it changes whenever the
+// post source, the templates, the config, the parts of its series or its
+// related posts change.
+func PostKey(ssg *models.SSG, templatePath string, post models.Post) string {
+	parts := []string{ssg.ConfigHash, ssg.TemplateHash, templatePath, post.SourceHash, post.Frontmatter.Slug}
+	for _, series := range ssg.Series[post.SourcePath] {
+		parts = append(parts, series.Taxonomy, series.Name, strconv.Itoa(series.Part))
+		for _, part := range series.Posts {
+			parts = append(parts, part.SourceHash, part.Frontmatter.Slug)
+		}
+	}
+	for _, related := range ssg.Related[post.SourcePath] {
+		parts = append(parts, related.SourceHash, related.Frontmatter.Slug)
+	}
+	return cache.Hash(parts...)
+}
+
+// FeedKey is the build cache key of a page listing posts, it changes when
+// any of the listed posts, their order, the templates or the config change.
+func FeedKey(ssg *models.SSG, templatePath string, feed models.Feed) string {
+	parts := []string{ssg.ConfigHash, ssg.TemplateHash, templatePath, feed.Title, feed.Type, feed.Slug}
+	for _, post := range feed.Posts {
+		parts = append(parts, post.SourceHash, post.Frontmatter.Slug)
+	}
+	return cache.Hash(parts...)
+}
+
+// PageKey is the build cache key of a page of a feed, it also changes
+// with the number of pages the page links to.
+func PageKey(ssg *models.SSG, templatePath string, page FeedPage) string {
+	return cache.Hash(FeedKey(ssg, templatePath, page.Feed), strconv.Itoa(page.Paginator.Page), strconv.Itoa(page.Paginator.TotalPages))
+}
+
+// WriteOutput writes a generated file like a feed or a sitemap, keyed by
+// its content, so it is only written again when it changes and pruned once
+// a build stops generating it. It reports whether the file was written.
+func WriteOutput(ssg *models.SSG, output string, data []byte) (bool, error) {
+	key := cache.Hash(string(data))
+	if ssg.Cache.Fresh(output, key) {
+		return false, nil
+	}
+	err := writeOutput(ssg, output, key, data)
+	return err == nil, err
+}
+
+// writeOutput writes data to output and records it under key.
+func writeOutput(ssg *models.SSG, output, key string, data []byte) error {
+	err := os.MkdirAll(filepath.Dir(output), os.ModePerm)
+	if err != nil {
+		return PageError("", output, err)
+	}
+	err = os.WriteFile(output, data, 0660)
+	if err != nil {
+		return PageError("", output, err)
+	}
+	ssg.Cache.Record(output, key)
+	return nil
+}
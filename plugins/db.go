@@ -25,20 +25,24 @@ import (
 )
 
 type DbPlugin struct {
-	PluginName string
+	PluginName string `json:"-"`
 }
 
 func (p *DbPlugin) Name() string {
 	return p.PluginName
 }
 
-func (p *DbPlugin) Execute(ssg *models.SSG) {
+func (p *DbPlugin) Requires() []string {
+	return []string{"renderTemplates"}
+}
+
+func (p *DbPlugin) Execute(ssg *models.SSG) error {
 	log.Println("------Executing DB plugin")
 
 	buffer := bytes.Buffer{}
-	templates, err := template.New("base").ParseFS(ssg.FS, "*.html")
+	templates, err := template.New("base").Funcs(TemplateFuncs).ParseFS(ssg.FS, "*.html")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	postContext := models.TemplateContext{
 		Themes: models.ThemeCombo{
@@ -49,15 +53,18 @@ func (p *DbPlugin) Execute(ssg *models.SSG) {
 			Blog: ssg.Config.Blog,
 		},
 	}
+	outputPath := filepath.Join(ssg.Config.Blog.OutputDir, "editor", "index.html")
 	err = templates.ExecuteTemplate(&buffer, "editor_template.html", postContext)
 	if err != nil {
-		log.Fatal(err)
+		return PageError("", outputPath, err)
+	}
+	err = os.MkdirAll(filepath.Dir(outputPath), os.ModePerm)
+	if err != nil {
+		return PageError("", outputPath, err)
 	}
-	err = os.MkdirAll(filepath.Join(".", ssg.Config.Blog.OutputDir, "editor"), os.ModePerm)
-	outputPath := filepath.Join(".", ssg.Config.Blog.OutputDir, "editor", "index.html")
 	err = os.WriteFile(outputPath, buffer.Bytes(), 0660)
 	if err != nil {
-		log.Fatal(err)
+		return PageError("", outputPath, err)
 	}
 	/*
 		dbURL := os.Getenv("TURSO_DATABASE_NAME")
@@ -118,6 +125,7 @@ func (p *DbPlugin) Execute(ssg *models.SSG) {
 		}
 		log.Printf("Created %d posts", len(createdPosts))
 	*/
+	return nil
 }
 
 func GetAllPostsSlug(posts []models.Post) []string {
@@ -163,7 +171,7 @@ func PostHandler(w http.ResponseWriter, r *http.Request) {
 	queries := libsqlssg.New(db)
 	err = r.ParseForm()
 	if err != nil {
-		fmt.Errorf("error: %s", err)
+		log.Printf("error: %s", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 	}
 	//fmt.Println(string(body))
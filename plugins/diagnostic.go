@@ -0,0 +1,81 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Diagnostic is an error found while building, tied to the plugin that
+// returned it and, when known, the source file, the line of it and the
+// output path it was found on.
+type Diagnostic struct {
+	Plugin string
+	Source string
+	Line   int
+	Output string
+	Err    error
+}
+
+func (d *Diagnostic) Error() string {
+	location := []string{}
+	if d.Plugin != "" {
+		location = append(location, d.Plugin)
+	}
+	if d.Source != "" && d.Line > 0 {
+		location = append(location, fmt.Sprintf("%s:%d", d.Source, d.Line))
+	} else if d.Source != "" {
+		location = append(location, d.Source)
+	}
+	if d.Output != "" {
+		location = append(location, "-> "+d.Output)
+	}
+	if len(location) == 0 {
+		return d.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", strings.Join(location, " "), d.Err)
+}
+
+func (d *Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// PageError wraps err as a failure to produce the page at output from the
+// source file, either of which may be empty.
+func PageError(source, output string, err error) error {
+	return &Diagnostic{Source: source, Output: output, Err: err}
+}
+
+// Diagnostics flattens err, which may join several errors, into one
+// diagnostic per error, each attributed to plugin.
+func Diagnostics(plugin string, err error) []*Diagnostic {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		diagnostics := []*Diagnostic{}
+		for _, err := range joined.Unwrap() {
+			diagnostics = append(diagnostics, Diagnostics(plugin, err)...)
+		}
+		return diagnostics
+	}
+	var diagnostic *Diagnostic
+	if errors.As(err, &diagnostic) {
+		if diagnostic.Plugin == "" {
+			diagnostic.Plugin = plugin
+		}
+		return []*Diagnostic{diagnostic}
+	}
+	return []*Diagnostic{{Plugin: plugin, Err: err}}
+}
+
+// PageErrorsOnly reports whether every diagnostic concerns a single page,
+// meaning the plugin still did the rest of its work.
+func PageErrorsOnly(diagnostics []*Diagnostic) bool {
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Source == "" && diagnostic.Output == "" {
+			return false
+		}
+	}
+	return true
+}
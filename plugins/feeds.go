@@ -0,0 +1,363 @@
+package plugins
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mr-destructive/mr-destructive.github.io/cache"
+	"github.com/mr-destructive/mr-destructive.github.io/feed"
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// FeedsPlugin writes the newest posts of the site as rss.xml, atom.xml and
+// feed.json, and those of every type and of every term of Taxonomies as
+// feed.xml, atom.xml and feed.json in the directory of their feed page.
+// Language is the language of the feeds, Limit the maximum number of items
+// of each, 0 for all, Formats picks some of rss, atom and json, and Types
+// set to false leaves out the feeds of the types.
+type FeedsPlugin struct {
+	PluginName string   `json:"-"`
+	Language   string   `json:"language"`
+	Limit      int      `json:"limit"`
+	Formats    []string `json:"formats"`
+	Types      *bool    `json:"types"`
+	Taxonomies []string `json:"taxonomies"`
+}
+
+// feedFormats are the files of each format for the site and for a feed
+// page, with its name and media type and the function writing it.
+var feedFormats = map[string]struct {
+	file, pageFile, name, mediaType string
+	write                           func(feed.Feed) ([]byte, error)
+}{
+	"rss":  {"rss.xml", "feed.xml", "RSS", "application/rss+xml", feed.Feed.RSS},
+	"atom": {"atom.xml", "atom.xml", "Atom", "application/atom+xml", feed.Feed.Atom},
+	"json": {"feed.json", "feed.json", "JSON Feed", "application/feed+json", feed.Feed.JSON},
+}
+
+func (p *FeedsPlugin) Name() string {
+	return p.PluginName
+}
+
+func (p *FeedsPlugin) Requires() []string {
+	return []string{"readPosts"}
+}
+
+func (p *FeedsPlugin) formats() ([]string, error) {
+	if len(p.Formats) == 0 {
+		return []string{"rss", "atom", "json"}, nil
+	}
+	for _, format := range p.Formats {
+		if _, ok := feedFormats[format]; !ok {
+			return nil, fmt.Errorf("unknown feed format %q, not rss, atom or json", format)
+		}
+	}
+	return p.Formats, nil
+}
+
+// sources returns the site and the feed pages getting a feed, the site
+// first, with their posts cleaned like on their pages.
+func (p *FeedsPlugin) sources(ssg *models.SSG) ([]models.Feed, error) {
+	blog := ssg.Config.Blog
+	site := models.Feed{Title: blog.Name, Slug: blog.PrefixURL}
+	types := []models.Feed{}
+	index := make(map[string]int)
+	for _, post := range ssg.Posts {
+		CleanPostFrontmatter(&post, ssg)
+		site.Posts = append(site.Posts, post)
+		postType := post.Frontmatter.Type
+		i, ok := index[postType]
+		if !ok {
+			i = len(types)
+			index[postType] = i
+			types = append(types, models.Feed{
+				Title: blog.Name + ": " + postType,
+				Type:  postType,
+				Slug:  blog.PrefixURL + postType,
+			})
+		}
+		types[i].Posts = append(types[i].Posts, post)
+	}
+
+	sources := []models.Feed{site}
+	if p.Types == nil || *p.Types {
+		slices.SortFunc(types, func(a, b models.Feed) int {
+			return strings.Compare(a.Type, b.Type)
+		})
+		sources = append(sources, types...)
+	}
+	for _, name := range p.Taxonomies {
+		taxonomy, ok := ssg.Config.Taxonomies[name]
+		if !ok {
+			return nil, fmt.Errorf("no taxonomy %q for the feeds", name)
+		}
+		for _, term := range Terms(ssg, taxonomy) {
+			term.Title = blog.Name + ": " + term.Title
+			sources = append(sources, term)
+		}
+	}
+	return sources, nil
+}
+
+// feedPath returns the path of the feed of source in format, relative to
+// the site root like its slug.
+func feedPath(blog models.BlogConfig, source models.Feed, format string) string {
+	if source.Slug == blog.PrefixURL {
+		return blog.PrefixURL + feedFormats[format].file
+	}
+	return source.Slug + "/" + feedFormats[format].pageFile
+}
+
+// BeforeRender links the feeds to their pages before the pages are
+// rendered, for the templates to advertise them.
+func (p *FeedsPlugin) BeforeRender(ssg *models.SSG) error {
+	formats, err := p.formats()
+	if err != nil {
+		return err
+	}
+	sources, err := p.sources(ssg)
+	if err != nil {
+		return err
+	}
+	blog := ssg.Config.Blog
+	ssg.FeedLinks = make(map[string][]models.FeedLink)
+	for _, source := range sources {
+		for _, format := range formats {
+			ssg.FeedLinks[source.Slug] = append(ssg.FeedLinks[source.Slug], models.FeedLink{
+				Title:  source.Title,
+				Format: feedFormats[format].name,
+				Type:   feedFormats[format].mediaType,
+				URL:    AbsoluteURL(blog, feedPath(blog, source, format)),
+			})
+		}
+	}
+	return nil
+}
+
+// Execute writes the feeds of the site pass, the admin copy links to them.
+// A feed is only generated again when one of the posts it keeps changed.
+func (p *FeedsPlugin) Execute(ssg *models.SSG) error {
+	if ssg.Config.AdminMode {
+		return nil
+	}
+	formats, err := p.formats()
+	if err != nil {
+		return err
+	}
+	sources, err := p.sources(ssg)
+	if err != nil {
+		return err
+	}
+	blog := ssg.Config.Blog
+	language := p.Language
+	if language == "" {
+		language = "en-us"
+	}
+	written := 0
+	// a post is in the feeds of the site, of its type and of its terms, its
+	// content is made absolute once
+	contents := make(map[string]string)
+	for _, source := range sources {
+		posts := newestPosts(source.Posts, p.Limit)
+		key := feedKey(ssg, source, posts)
+		outputs := make(map[string]string)
+		fresh := true
+		for _, format := range formats {
+			path := feedPath(blog, source, format)
+			outputs[format] = filepath.Join(blog.OutputDir, strings.TrimPrefix(path, blog.PrefixURL))
+			fresh = ssg.Cache.Fresh(outputs[format], cache.Hash(key, format)) && fresh
+		}
+		if fresh {
+			continue
+		}
+		document := feed.Feed{
+			Title:       source.Title,
+			Description: blog.Description,
+			Language:    language,
+			HomeURL:     AbsoluteURL(blog, source.Slug),
+			Authors:     feedAuthors(ssg.Config.Authors),
+			Items:       feedItems(ssg, posts, contents),
+		}
+		for _, format := range formats {
+			output := outputs[format]
+			document.FeedURL = AbsoluteURL(blog, feedPath(blog, source, format))
+			data, err := feedFormats[format].write(document)
+			if err != nil {
+				return PageError("", output, fmt.Errorf("generating the %s feed: %w", format, err))
+			}
+			err = writeOutput(ssg, output, cache.Hash(key, format), data)
+			if err != nil {
+				return err
+			}
+			written++
+		}
+	}
+	fmt.Printf("Feeds generated: %d written for %d pages\n", written, len(sources))
+	return nil
+}
+
+// feedKey is the build cache key of the feeds of source, it changes when
+// any of the posts it keeps, their order or the config change.
+func feedKey(ssg *models.SSG, source models.Feed, posts []models.Post) string {
+	parts := []string{ssg.ConfigHash, source.Title, source.Slug}
+	for _, post := range posts {
+		parts = append(parts, post.SourceHash, post.Frontmatter.Slug)
+	}
+	return cache.Hash(parts...)
+}
+
+// AbsoluteURL returns the URL of path, relative to the site root like the
+// slugs of posts, on the base URL of the site, which is taken as https
+// when it has no scheme.
+func AbsoluteURL(blog models.BlogConfig, path string) string {
+	base := strings.TrimSuffix(blog.BaseUrl, "/")
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	return base + "/" + strings.TrimPrefix(path, "/")
+}
+
+// FeedItems returns the published posts of a feed as feed items, newest
+// first and at most limit of them unless limit is 0. The posts are cleaned
+// like the posts of feeds, with slugs relative to the site root.
+func FeedItems(ssg *models.SSG, posts []models.Post, limit int) []feed.Item {
+	return feedItems(ssg, newestPosts(posts, limit), nil)
+}
+
+// newestPosts returns the published posts, newest first and at most limit
+// of them unless limit is 0.
+func newestPosts(posts []models.Post, limit int) []models.Post {
+	published := []models.Post{}
+	for _, post := range posts {
+		if _, ok := parseDay(post.Frontmatter.Date); ok && post.Frontmatter.Status != "draft" {
+			published = append(published, post)
+		}
+	}
+	slices.SortStableFunc(published, func(a, b models.Post) int {
+		dayA, _ := parseDay(a.Frontmatter.Date)
+		dayB, _ := parseDay(b.Frontmatter.Date)
+		return dayB.Compare(dayA)
+	})
+	if limit > 0 && len(published) > limit {
+		published = published[:limit]
+	}
+	return published
+}
+
+// feedItems returns posts as feed items, with their contents made absolute
+// once in contents when it is not nil.
+func feedItems(ssg *models.SSG, posts []models.Post, contents map[string]string) []feed.Item {
+	blog := ssg.Config.Blog
+	items := []feed.Item{}
+	for _, post := range posts {
+		published, _ := parseDay(post.Frontmatter.Date)
+		url := AbsoluteURL(blog, post.Frontmatter.Slug)
+		item := feed.Item{
+			ID:          url,
+			URL:         url,
+			Title:       post.Frontmatter.Title,
+			Summary:     post.Frontmatter.Description,
+			ContentHTML: feedContent(ssg, post, contents),
+			Published:   published,
+			Categories:  post.Frontmatter.Tags,
+			Authors:     postAuthors(ssg.Config.Authors, post),
+		}
+		if updated, ok := lastModified(post); ok && updated.After(published) {
+			item.Updated = updated
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// feedContent returns the content of post with its links absolute, kept
+// in contents by post.
+func feedContent(ssg *models.SSG, post models.Post, contents map[string]string) string {
+	key := post.SourcePath + " " + post.Frontmatter.Slug
+	if content, ok := contents[key]; ok {
+		return content
+	}
+	content := absoluteLinks(ssg.Config.Blog, string(post.Content))
+	if contents != nil {
+		contents[key] = content
+	}
+	return content
+}
+
+// parseDay parses the day of a front matter date, which may have a time.
+func parseDay(date string) (time.Time, bool) {
+	day, err := time.Parse("2006-01-02", date[:min(len(date), 10)])
+	return day, err == nil
+}
+
+// lastModified returns the day post was last changed on, from its updated
+// or lastmod front matter key, and false when neither is set.
+func lastModified(post models.Post) (time.Time, bool) {
+	var last time.Time
+	for _, key := range []string{"updated", "lastmod"} {
+		if day, ok := parseDay(fmt.Sprint(post.Frontmatter.Extras[key])); ok && day.After(last) {
+			last = day
+		}
+	}
+	return last, !last.IsZero()
+}
+
+// rootRelative matches the links and images of the content relative to the
+// site root, which readers would resolve against the feed host otherwise.
+var rootRelative = regexp.MustCompile(`(\s(?:href|src)=")/([^/"])`)
+
+// srcset matches the srcset attributes of images, whose candidates may be
+// relative to the site root as well.
+var srcset = regexp.MustCompile(`(\ssrcset=")([^"]*)`)
+
+func absoluteLinks(blog models.BlogConfig, html string) string {
+	html = rootRelative.ReplaceAllString(html, "${1}"+AbsoluteURL(blog, "")+"${2}")
+	return srcset.ReplaceAllStringFunc(html, func(attr string) string {
+		match := srcset.FindStringSubmatch(attr)
+		candidates := strings.Split(match[2], ",")
+		for i, candidate := range candidates {
+			candidate = strings.TrimSpace(candidate)
+			if strings.HasPrefix(candidate, "/") && !strings.HasPrefix(candidate, "//") {
+				candidate = AbsoluteURL(blog, candidate)
+			}
+			candidates[i] = candidate
+		}
+		return match[1] + strings.Join(candidates, ", ")
+	})
+}
+
+func feedAuthors(authors []models.Author) []feed.Author {
+	list := []feed.Author{}
+	for _, author := range authors {
+		feedAuthor := feed.Author{Name: author.Name, Email: author.Email}
+		if author.Github != "" {
+			feedAuthor.URL = "https://" + strings.TrimPrefix(author.Github, "https://")
+		}
+		list = append(list, feedAuthor)
+	}
+	return list
+}
+
+// postAuthors returns the authors of the config named by the author key of
+// the front matter, by username or name. Posts without one are by the
+// authors of the feed.
+func postAuthors(authors []models.Author, post models.Post) []feed.Author {
+	name, _ := post.Frontmatter.Extras["author"].(string)
+	for _, author := range authors {
+		if name != "" && (name == author.Username || name == author.Name) {
+			return feedAuthors([]models.Author{author})
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterPlugin("Feeds", reflect.TypeOf(FeedsPlugin{
+		PluginName: "Feeds",
+	}))
+}
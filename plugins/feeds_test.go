@@ -0,0 +1,66 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+func TestAbsoluteLinks(t *testing.T) {
+	blog := models.BlogConfig{BaseUrl: "example.com/"}
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"root-relative link", `<a href="/posts/a">a</a>`, `<a href="https://example.com/posts/a">a</a>`},
+		{"image", `<img src="/img/a.png">`, `<img src="https://example.com/img/a.png">`},
+		{"protocol-relative", `<img src="//cdn.example.com/a.png">`, `<img src="//cdn.example.com/a.png">`},
+		{"absolute", `<a href="https://other.com/a">a</a>`, `<a href="https://other.com/a">a</a>`},
+		{"relative", `<a href="a.html">a</a>`, `<a href="a.html">a</a>`},
+		{"fragment", `<a href="#intro">a</a>`, `<a href="#intro">a</a>`},
+		{"escaped text", `Write href=&quot;/a&quot; in HTML`, `Write href=&quot;/a&quot; in HTML`},
+		{
+			"srcset",
+			`<img srcset="/a-480w.jpg 480w, //cdn.com/b.jpg 800w,https://x.com/c.jpg 1200w">`,
+			`<img srcset="https://example.com/a-480w.jpg 480w, //cdn.com/b.jpg 800w, https://x.com/c.jpg 1200w">`,
+		},
+	}
+	for _, test := range tests {
+		if got := absoluteLinks(blog, test.html); got != test.want {
+			t.Errorf("%s: absoluteLinks(%q) = %q, want %q", test.name, test.html, got, test.want)
+		}
+	}
+}
+
+func TestNewestPosts(t *testing.T) {
+	post := func(slug, date, status string) models.Post {
+		return models.Post{Frontmatter: models.FrontMatter{Slug: slug, Date: date, Status: status}}
+	}
+	posts := []models.Post{
+		post("old", "2023-05-01", ""),
+		post("draft", "2024-03-01", "draft"),
+		post("new", "2024-02-01T10:00:00Z", "published"),
+		post("undated", "", ""),
+		post("same-day-first", "2023-06-01", ""),
+		post("same-day-second", "2023-06-01", ""),
+	}
+	tests := []struct {
+		limit int
+		want  string
+	}{
+		{0, "new same-day-first same-day-second old"},
+		{2, "new same-day-first"},
+		{10, "new same-day-first same-day-second old"},
+	}
+	for _, test := range tests {
+		slugs := []string{}
+		for _, post := range newestPosts(posts, test.limit) {
+			slugs = append(slugs, post.Frontmatter.Slug)
+		}
+		if got := strings.Join(slugs, " "); got != test.want {
+			t.Errorf("newestPosts(limit %d) = %s, want %s", test.limit, got, test.want)
+		}
+	}
+}
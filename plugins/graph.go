@@ -0,0 +1,130 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+type Hook int
+
+const (
+	HookBeforeRead Hook = iota
+	HookAfterRead
+	HookBeforeRender
+	HookAfterRender
+	HookAfterWrite
+)
+
+// PhaseOf returns the phase plugin executes in.
+func PhaseOf(plugin Plugin) Phase {
+	if phased, ok := plugin.(Phased); ok {
+		return phased.Phase()
+	}
+	return PhaseRender
+}
+
+// Sort orders plugins so that each one comes after the plugins it
+// requires, keeping the order of the list otherwise, and then groups them
+// by phase. A missing dependency, a dependency executing in a later phase
+// or a dependency cycle is an error.
+func Sort(list []Plugin) ([]Plugin, error) {
+	byName := make(map[string]Plugin)
+	for _, plugin := range list {
+		byName[plugin.Name()] = plugin
+	}
+	for _, plugin := range list {
+		for _, dep := range requires(plugin) {
+			required, ok := byName[dep]
+			if !ok {
+				return nil, fmt.Errorf("plugin %s requires %s, which is not in the plugins list", plugin.Name(), dep)
+			}
+			if PhaseOf(required) > PhaseOf(plugin) {
+				return nil, fmt.Errorf("plugin %s requires %s, which executes in a later phase", plugin.Name(), dep)
+			}
+		}
+	}
+
+	sorted := []Plugin{}
+	state := make(map[string]int) // 0 unvisited, 1 visiting, 2 done
+	var visit func(plugin Plugin, path []string) error
+	visit = func(plugin Plugin, path []string) error {
+		name := plugin.Name()
+		path = append(path, name)
+		switch state[name] {
+		case 1:
+			start := 0
+			for path[start] != name {
+				start++
+			}
+			return fmt.Errorf("plugin dependency cycle: %s", strings.Join(path[start:], " -> "))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, dep := range requires(plugin) {
+			if err := visit(byName[dep], path); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		sorted = append(sorted, plugin)
+		return nil
+	}
+	for _, plugin := range list {
+		if err := visit(plugin, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	phased := []Plugin{}
+	for _, phase := range []Phase{PhaseRead, PhaseRender, PhaseWrite} {
+		for _, plugin := range sorted {
+			if PhaseOf(plugin) == phase {
+				phased = append(phased, plugin)
+			}
+		}
+	}
+	return phased, nil
+}
+
+func requires(plugin Plugin) []string {
+	if dependent, ok := plugin.(Dependent); ok {
+		return dependent.Requires()
+	}
+	return nil
+}
+
+// RunHook calls hook on every plugin of list implementing it, and returns
+// the diagnostics of the plugins that failed.
+func RunHook(list []Plugin, hook Hook, ssg *models.SSG) []*Diagnostic {
+	diagnostics := []*Diagnostic{}
+	for _, plugin := range list {
+		var err error
+		switch hook {
+		case HookBeforeRead:
+			if h, ok := plugin.(BeforeReadHook); ok {
+				err = h.BeforeRead(ssg)
+			}
+		case HookAfterRead:
+			if h, ok := plugin.(AfterReadHook); ok {
+				err = h.AfterRead(ssg)
+			}
+		case HookBeforeRender:
+			if h, ok := plugin.(BeforeRenderHook); ok {
+				err = h.BeforeRender(ssg)
+			}
+		case HookAfterRender:
+			if h, ok := plugin.(AfterRenderHook); ok {
+				err = h.AfterRender(ssg)
+			}
+		case HookAfterWrite:
+			if h, ok := plugin.(AfterWriteHook); ok {
+				err = h.AfterWrite(ssg)
+			}
+		}
+		diagnostics = append(diagnostics, Diagnostics(plugin.Name(), err)...)
+	}
+	return diagnostics
+}
@@ -0,0 +1,108 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// testPlugin is a plugin named name, requiring deps and executing in
+// phase.
+type testPlugin struct {
+	name  string
+	deps  []string
+	phase Phase
+}
+
+func (p testPlugin) Name() string                  { return p.name }
+func (p testPlugin) Execute(ssg *models.SSG) error { return nil }
+func (p testPlugin) Requires() []string            { return p.deps }
+func (p testPlugin) Phase() Phase                  { return p.phase }
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugins []testPlugin
+		want    string
+		err     string
+	}{
+		{
+			name: "no dependencies keep their order",
+			plugins: []testPlugin{
+				{name: "a", phase: PhaseRender},
+				{name: "b", phase: PhaseRender},
+				{name: "c", phase: PhaseRender},
+			},
+			want: "a b c",
+		},
+		{
+			name: "dependencies first",
+			plugins: []testPlugin{
+				{name: "feeds", deps: []string{"posts"}, phase: PhaseRender},
+				{name: "posts", phase: PhaseRender},
+				{name: "index", deps: []string{"feeds"}, phase: PhaseRender},
+			},
+			want: "posts feeds index",
+		},
+		{
+			name: "grouped by phase",
+			plugins: []testPlugin{
+				{name: "write", phase: PhaseWrite},
+				{name: "render", phase: PhaseRender},
+				{name: "read", phase: PhaseRead},
+				{name: "render2", deps: []string{"read"}, phase: PhaseRender},
+			},
+			want: "read render render2 write",
+		},
+		{
+			name:    "missing dependency",
+			plugins: []testPlugin{{name: "feeds", deps: []string{"Posts"}, phase: PhaseRender}, {name: "posts", phase: PhaseRender}},
+			err:     "plugin feeds requires Posts, which is not in the plugins list",
+		},
+		{
+			name:    "dependency in a later phase",
+			plugins: []testPlugin{{name: "read", phase: PhaseRead, deps: []string{"render"}}, {name: "render", phase: PhaseRender}},
+			err:     "plugin read requires render, which executes in a later phase",
+		},
+		{
+			name: "cycle",
+			plugins: []testPlugin{
+				{name: "a", deps: []string{"b"}, phase: PhaseRender},
+				{name: "b", deps: []string{"c"}, phase: PhaseRender},
+				{name: "c", deps: []string{"a"}, phase: PhaseRender},
+			},
+			err: "plugin dependency cycle: a -> b -> c -> a",
+		},
+		{
+			name:    "self dependency",
+			plugins: []testPlugin{{name: "a", deps: []string{"a"}, phase: PhaseRender}},
+			err:     "plugin dependency cycle: a -> a",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			list := []Plugin{}
+			for _, plugin := range test.plugins {
+				list = append(list, plugin)
+			}
+			sorted, err := Sort(list)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("Sort() error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, plugin := range sorted {
+				names = append(names, plugin.Name())
+			}
+			if got := strings.Join(names, " "); got != test.want {
+				t.Errorf("Sort() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// HighlightStylesheet is the stylesheet written next to the static files
+// when code blocks are highlighted at build time.
+const HighlightStylesheet = "highlight.css"
+
+// HighlightCSS returns the stylesheet colouring the classes of highlighted
+// code blocks with the code colours of the theme in use, the custom
+// properties of the theme stylesheet.
+func HighlightCSS() []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, ".chroma .%s, .chroma .%s { margin-right: 0.8em; user-select: none; }\n",
+		chroma.StandardTypes[chroma.LineNumbers], chroma.StandardTypes[chroma.LineNumbersTable])
+	colors := map[chroma.TokenType]string{
+		chroma.Comment:              "--code-comment-color",
+		chroma.Keyword:              "--code-keyword-color",
+		chroma.LiteralString:        "--code-string-color",
+		chroma.LiteralNumber:        "--code-number-color",
+		chroma.NameVariable:         "--code-variable-color",
+		chroma.NameVariableClass:    "--code-variable-color",
+		chroma.NameVariableGlobal:   "--code-variable-color",
+		chroma.NameVariableInstance: "--code-variable-color",
+		chroma.NameVariableMagic:    "--code-variable-color",
+		chroma.NameFunction:         "--code-function-color",
+		chroma.NameFunctionMagic:    "--code-function-color",
+		chroma.NameBuiltin:          "--code-function-color",
+		chroma.LineNumbers:          "--code-comment-color",
+		chroma.LineNumbersTable:     "--code-comment-color",
+	}
+
+	buffer.WriteString(".chroma { color: var(--code-text-color); background-color: var(--code-bg-color); }\n")
+	fmt.Fprintf(&buffer, ".chroma .%s { background-color: var(--code-border-color); display: block; }\n", chroma.StandardTypes[chroma.LineHighlight])
+	rules := []string{}
+	for tokenType, class := range chroma.StandardTypes {
+		color := tokenColor(colors, tokenType)
+		if color == "" || class == "" {
+			continue
+		}
+		rules = append(rules, fmt.Sprintf(".chroma .%s { color: var(%s); }\n", class, color))
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		buffer.WriteString(rule)
+	}
+	return buffer.Bytes()
+}
+
+// tokenColor returns the colour of tokenType, or of the closest of its
+// parents with one.
+func tokenColor(colors map[chroma.TokenType]string, tokenType chroma.TokenType) string {
+	for _, candidate := range []chroma.TokenType{tokenType, tokenType.SubCategory(), tokenType.Category()} {
+		if color := colors[candidate]; color != "" {
+			return color
+		}
+	}
+	return ""
+}
@@ -0,0 +1,339 @@
+package plugins
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"image"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mr-destructive/mr-destructive.github.io/cache"
+	"github.com/mr-destructive/mr-destructive.github.io/images"
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// ImagesPlugin makes resized variants of the local images of posts, those
+// of the static dir and those next to the post file, and rewrites their
+// <img> tags with a srcset of the variants, the width and height of the
+// image so the page does not shift as it loads, and lazy loading. Images
+// next to a post are copied next to its page. Variants are only made again
+// when their image or the options change.
+type ImagesPlugin struct {
+	PluginName string `json:"-"`
+	// Widths are the widths of the variants, an image only gets those
+	// narrower than itself; 480, 800 and 1200 if empty
+	Widths []int `json:"widths"`
+	// Sizes is the sizes attribute of the rewritten tags, 100vw if empty
+	Sizes string `json:"sizes"`
+	// Quality is the quality of the JPEG variants, from 1 to 100, 80 if
+	// zero
+	Quality int `json:"quality"`
+	// Lazy adds loading="lazy" to the tags without a loading attribute,
+	// true if unset
+	Lazy *bool `json:"lazy"`
+
+	// images are the images found by BeforeRender, by output dir and name
+	images map[string]*localImage
+}
+
+// localImage is an image file and the outputs made from it.
+type localImage struct {
+	source string
+	info   images.Info
+	// dir and url are the output directory of the variants and its URL
+	dir string
+	url string
+	// name is the name of the image in dir
+	name   string
+	widths []int
+	// copied images are written to dir along with their variants, those of
+	// the static dir are already copied there
+	copied bool
+}
+
+func (i *localImage) src() string {
+	return i.url + "/" + i.name
+}
+
+func (i *localImage) srcset() string {
+	candidates := []string{}
+	for _, width := range i.widths {
+		candidates = append(candidates, fmt.Sprintf("%s/%s %dw", i.url, images.VariantName(i.name, i.info.Hash, width), width))
+	}
+	return strings.Join(append(candidates, fmt.Sprintf("%s %dw", i.src(), i.info.Width)), ", ")
+}
+
+func (p *ImagesPlugin) Name() string {
+	return p.PluginName
+}
+
+func (p *ImagesPlugin) Requires() []string {
+	return []string{"readPosts", "copyStaticFiles"}
+}
+
+func (p *ImagesPlugin) widths() []int {
+	if len(p.Widths) == 0 {
+		return []int{480, 800, 1200}
+	}
+	return p.Widths
+}
+
+func (p *ImagesPlugin) quality() int {
+	if p.Quality <= 0 || p.Quality > 100 {
+		return 80
+	}
+	return p.Quality
+}
+
+// BeforeRender rewrites the <img> tags of the posts before their pages are
+// rendered. The source hash of a post with local images changes with them,
+// so that its pages are rendered again when one does.
+func (p *ImagesPlugin) BeforeRender(ssg *models.SSG) error {
+	p.images = make(map[string]*localImage)
+	sizes := p.Sizes
+	if sizes == "" {
+		sizes = "100vw"
+	}
+	var errs []error
+	for i := range ssg.Posts {
+		post := &ssg.Posts[i]
+		hashes := []string{post.SourceHash}
+		content := images.RewriteTags(string(post.Content), func(tag *images.Tag) bool {
+			src, ok := tag.Get("src")
+			if !ok || tag.Has("srcset") {
+				return false
+			}
+			img, err := p.resolve(ssg, *post, src)
+			if err != nil {
+				errs = append(errs, PageError(post.SourcePath, "", fmt.Errorf("image %q: %w", src, err)))
+				return false
+			}
+			if img == nil {
+				return false
+			}
+			hashes = append(hashes, img.info.Hash)
+			if img.copied {
+				tag.Set("src", img.src())
+			}
+			if len(img.widths) > 0 {
+				tag.Set("srcset", img.srcset())
+				tag.Set("sizes", sizes)
+			}
+			if !tag.Has("width") && !tag.Has("height") {
+				tag.Set("width", strconv.Itoa(img.info.Width))
+				tag.Set("height", strconv.Itoa(img.info.Height))
+			}
+			if (p.Lazy == nil || *p.Lazy) && !tag.Has("loading") {
+				tag.Set("loading", "lazy")
+			}
+			return true
+		})
+		// an image_url of the static dir is already copied
+		if imageURL := post.Frontmatter.ImageUrl; imageURL != "" && !strings.HasPrefix(imageURL, "/") {
+			img, err := p.resolve(ssg, *post, imageURL)
+			if err != nil {
+				errs = append(errs, PageError(post.SourcePath, "", fmt.Errorf("image_url %q: %w", imageURL, err)))
+			} else if img != nil && img.copied {
+				hashes = append(hashes, img.info.Hash)
+				post.Frontmatter.ImageUrl = img.src()
+			}
+		}
+		if len(hashes) > 1 {
+			post.Content = template.HTML(content)
+			post.SourceHash = cache.Hash(hashes...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolve returns the local image src links to from post, nil when it is
+// not one: a remote image, a format without variants or a missing file.
+// A path from the site root is an image of the static dir or of the page
+// bundle of the post, a relative one an image next to the post file.
+func (p *ImagesPlugin) resolve(ssg *models.SSG, post models.Post, src string) (*localImage, error) {
+	ref, err := url.Parse(src)
+	if err != nil || ref.Scheme != "" || ref.Host != "" || ref.Path == "" || !images.Supported(ref.Path) {
+		return nil, nil
+	}
+	blog := ssg.Config.Blog
+	img := &localImage{}
+	root := strings.HasPrefix(ref.Path, "/")
+	asset, isAsset := ssg.Assets.Lookup(strings.TrimPrefix(strings.TrimPrefix(ref.Path, "/"), blog.PrefixURL))
+	pageURL := "/" + blog.PrefixURL + PostDir(post) + "/"
+	switch {
+	case root && isAsset:
+		img.source = asset.Source
+		img.dir = filepath.Join(blog.OutputDir, filepath.FromSlash(path.Dir(asset.Path)))
+		img.url = strings.TrimSuffix(path.Dir(ref.Path), "/")
+		img.name = path.Base(asset.Path)
+	case post.Bundle != "" && strings.HasPrefix(ref.Path, pageURL):
+		// the links of page bundles are resolved to their page, which
+		// the files of the bundle are copied next to
+		rel := strings.TrimPrefix(ref.Path, pageURL)
+		img.source = filepath.Join(post.Bundle, filepath.FromSlash(rel))
+		if info, err := os.Stat(img.source); err != nil || !info.Mode().IsRegular() {
+			return nil, nil
+		}
+		img.dir = filepath.Join(blog.OutputDir, filepath.FromSlash(path.Join(PostDir(post), path.Dir(rel))))
+		img.url = path.Dir(ref.Path)
+		img.name = path.Base(rel)
+	case root:
+		return nil, nil
+	default:
+		rel := path.Clean(ref.Path)
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return nil, nil
+		}
+		img.source = filepath.Join(filepath.Dir(post.SourcePath), filepath.FromSlash(rel))
+		if info, err := os.Stat(img.source); err != nil || !info.Mode().IsRegular() {
+			return nil, nil
+		}
+		pageDir := path.Join(PostDir(post), path.Dir(rel))
+		img.dir = filepath.Join(blog.OutputDir, filepath.FromSlash(pageDir))
+		img.url = "/" + blog.PrefixURL + pageDir
+		img.name = path.Base(rel)
+		img.copied = true
+	}
+
+	key := filepath.Join(img.dir, img.name)
+	if known, ok := p.images[key]; ok {
+		return known, nil
+	}
+	data, err := os.ReadFile(img.source)
+	if err != nil {
+		return nil, err
+	}
+	img.info, err = images.Inspect(data)
+	if err != nil {
+		return nil, err
+	}
+	img.widths = img.info.Widths(p.widths())
+	p.images[key] = img
+	return img, nil
+}
+
+// PostDir returns the path of the pages of post from the output dir, like
+// posts/my-post, from its raw front matter.
+func PostDir(post models.Post) string {
+	postType := post.Frontmatter.Type
+	if postType == "" {
+		postType = "posts"
+	}
+	slug := post.Frontmatter.Slug
+	if slug == "" {
+		slug = Slugify(post.Frontmatter.Title)
+	}
+	return path.Join(postType, slug)
+}
+
+// Execute writes the variants, and the copies of the images next to posts,
+// that are not already in the output dir.
+func (p *ImagesPlugin) Execute(ssg *models.SSG) error {
+	keys := []string{}
+	for key := range p.images {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	errs := make([]error, len(keys))
+	written := make([]int, len(keys))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range min(Workers(ssg), len(keys)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				written[i], errs[i] = p.write(ssg, p.images[keys[i]])
+			}
+		}()
+	}
+	for i := range keys {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	total := 0
+	for _, n := range written {
+		total += n
+	}
+	fmt.Println("Images:", len(keys), "local images,", total, "files written")
+	return errors.Join(errs...)
+}
+
+// write writes the outputs of image that are not fresh, decoding it at most
+// once, and returns how many it wrote.
+func (p *ImagesPlugin) write(ssg *models.SSG, img *localImage) (int, error) {
+	type output struct {
+		path  string
+		key   string
+		width int
+	}
+	outputs := []output{}
+	if img.copied {
+		outputs = append(outputs, output{path: filepath.Join(img.dir, img.name), key: img.info.Hash})
+	}
+	quality := strconv.Itoa(p.quality())
+	for _, width := range img.widths {
+		outputs = append(outputs, output{
+			path:  filepath.Join(img.dir, images.VariantName(img.name, img.info.Hash, width)),
+			key:   cache.Hash(img.info.Hash, strconv.Itoa(width), quality),
+			width: width,
+		})
+	}
+
+	var data []byte
+	var decoded image.Image
+	written := 0
+	for _, out := range outputs {
+		if ssg.Cache.Fresh(out.path, out.key) {
+			continue
+		}
+		var err error
+		if data == nil {
+			data, err = os.ReadFile(img.source)
+			if err != nil {
+				return written, PageError(img.source, out.path, err)
+			}
+		}
+		content := data
+		if out.width > 0 {
+			if decoded == nil {
+				decoded, _, err = image.Decode(bytes.NewReader(data))
+				if err != nil {
+					return written, PageError(img.source, out.path, err)
+				}
+			}
+			content, err = images.Encode(images.Scale(decoded, out.width), img.info.Format, p.quality())
+			if err != nil {
+				return written, PageError(img.source, out.path, err)
+			}
+		}
+		err = os.MkdirAll(filepath.Dir(out.path), os.ModePerm)
+		if err == nil {
+			err = os.WriteFile(out.path, content, 0660)
+		}
+		if err != nil {
+			return written, PageError(img.source, out.path, err)
+		}
+		ssg.Cache.Record(out.path, out.key)
+		written++
+	}
+	return written, nil
+}
+
+func init() {
+	RegisterPlugin("Images", reflect.TypeOf(ImagesPlugin{
+		PluginName: "Images",
+	}))
+}
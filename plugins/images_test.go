@@ -0,0 +1,144 @@
+package plugins
+
+import (
+	"bytes"
+	"html/template"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/cache"
+	"github.com/mr-destructive/mr-destructive.github.io/images"
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// imagesSite returns a site with a post next to a 1000x500 photo.png, whose
+// content is html.
+func imagesSite(t *testing.T, html string) (*models.SSG, string) {
+	t.Helper()
+	dir := t.TempDir()
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 1000, 500))); err != nil {
+		t.Fatal(err)
+	}
+	photo := filepath.Join(dir, "posts", "photo.png")
+	if err := os.MkdirAll(filepath.Dir(photo), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(photo, buffer.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	post := models.Post{Content: template.HTML(html), SourcePath: filepath.Join(dir, "posts", "a.md"), SourceHash: "source"}
+	post.Frontmatter.Slug = "a"
+	ssg := &models.SSG{Posts: []models.Post{post}}
+	ssg.Config.Blog.OutputDir = filepath.Join(dir, "public")
+	return ssg, dir
+}
+
+func TestImagesRewrite(t *testing.T) {
+	srcset := "/posts/a/photo.3f2a1c0b-480w.png 480w, /posts/a/photo.png 1000w"
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "local image",
+			html: `<img src="photo.png" alt="A">`,
+			want: `<img src="/posts/a/photo.png" alt="A" srcset="` + srcset + `" sizes="100vw" width="1000" height="500" loading="lazy">`,
+		},
+		{
+			name: "existing srcset kept",
+			html: `<img src="photo.png" srcset="photo-2x.png 2x">`,
+			want: `<img src="photo.png" srcset="photo-2x.png 2x">`,
+		},
+		{
+			name: "existing size and loading kept",
+			html: `<img src="photo.png" width="300" loading="eager">`,
+			want: `<img src="/posts/a/photo.png" width="300" loading="eager" srcset="` + srcset + `" sizes="100vw">`,
+		},
+		{
+			name: "remote and missing images left alone",
+			html: `<img src="https://example.com/a.png"><img src="missing.png"><img src="/static.png">`,
+			want: `<img src="https://example.com/a.png"><img src="missing.png"><img src="/static.png">`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ssg, _ := imagesSite(t, test.html)
+			plugin := &ImagesPlugin{Widths: []int{480, 1200}}
+			if err := plugin.BeforeRender(ssg); err != nil {
+				t.Fatal(err)
+			}
+			post := ssg.Posts[0]
+			var hash string
+			for _, img := range plugin.images {
+				hash = img.info.Hash
+			}
+			want := strings.ReplaceAll(test.want, "3f2a1c0b", hash[:min(len(hash), 8)])
+			if string(post.Content) != want {
+				t.Errorf("content = %s, want %s", post.Content, want)
+			}
+			// the pages of a post change with its images
+			if rewritten := test.want != test.html; rewritten == (post.SourceHash == "source") {
+				t.Errorf("source hash = %s after rewriting %v", post.SourceHash, rewritten)
+			}
+		})
+	}
+}
+
+func TestImagesWrite(t *testing.T) {
+	ssg, dir := imagesSite(t, `<img src="photo.png">`)
+	var err error
+	ssg.Cache, err = cache.Open(filepath.Join(dir, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin := &ImagesPlugin{Widths: []int{480, 800}}
+	if err := plugin.BeforeRender(ssg); err != nil {
+		t.Fatal(err)
+	}
+	img := plugin.images[filepath.Join(ssg.Config.Blog.OutputDir, "posts", "a", "photo.png")]
+	if img == nil {
+		t.Fatalf("images = %v, want photo.png", plugin.images)
+	}
+	written, err := plugin.write(ssg, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 3 {
+		t.Errorf("first write wrote %d files, want the copy and 2 variants", written)
+	}
+	for _, width := range []int{480, 800} {
+		data, err := os.ReadFile(filepath.Join(img.dir, images.VariantName("photo.png", img.info.Hash, width)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info, err := images.Inspect(data); err != nil || info.Width != width || info.Height != width/2 {
+			t.Errorf("variant at %d = %+v, %v", width, info, err)
+		}
+	}
+
+	written, err = plugin.write(ssg, img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != 0 {
+		t.Errorf("second write wrote %d files, want the fresh ones skipped", written)
+	}
+
+	// a variant deleted from the output dir is written again
+	os.Remove(filepath.Join(img.dir, images.VariantName("photo.png", img.info.Hash, 480)))
+	plugin.Quality = 80
+	if written, err = plugin.write(ssg, img); err != nil || written != 1 {
+		t.Errorf("write after a deleted variant = %d, %v, want 1 file", written, err)
+	}
+	// a new quality makes the variants again
+	plugin.Quality = 50
+	if written, err = plugin.write(ssg, img); err != nil || written != 2 {
+		t.Errorf("write at a new quality = %d, %v, want 2 files", written, err)
+	}
+}
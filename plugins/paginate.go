@@ -0,0 +1,69 @@
+package plugins
+
+import (
+	"path/filepath"
+	"strconv"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// FeedPage is a page of a feed split by Paginate, Feed only holds the
+// posts of the page.
+type FeedPage struct {
+	Feed      models.Feed
+	Paginator models.Paginator
+}
+
+// Paginate splits the posts of feed into pages of pageSize posts, there
+// is a single page when pageSize is zero or when feed has no posts.
+func Paginate(feed models.Feed, pageSize int) []FeedPage {
+	total := len(feed.Posts)
+	if pageSize <= 0 {
+		pageSize = max(total, 1)
+	}
+	totalPages := max((total+pageSize-1)/pageSize, 1)
+	pages := []FeedPage{}
+	for number := 1; number <= totalPages; number++ {
+		paginator := models.Paginator{
+			Page:       number,
+			TotalPages: totalPages,
+			TotalPosts: total,
+			PageSize:   pageSize,
+			BaseURL:    feed.Slug,
+		}
+		if number > 1 {
+			paginator.PrevURL = paginator.URL(number - 1)
+		}
+		if number < totalPages {
+			paginator.NextURL = paginator.URL(number + 1)
+		}
+		page := feed
+		page.Posts = feed.Posts[min((number-1)*pageSize, total):min(number*pageSize, total)]
+		pages = append(pages, FeedPage{Feed: page, Paginator: paginator})
+	}
+	return pages
+}
+
+// FeedPageJobs returns the jobs rendering the pages of feed with
+// templatePath, in context with the page set. The first page is written to
+// dir/index.html and page n to dir/page/n/index.html.
+func FeedPageJobs(ssg *models.SSG, templatePath string, feed models.Feed, pageSize int, dir string, context models.TemplateContext) []RenderJob {
+	jobs := []RenderJob{}
+	for _, page := range Paginate(feed, pageSize) {
+		context.FeedPosts = []models.Feed{page.Feed}
+		context.FeedInfo = page.Feed
+		context.Paginator = page.Paginator
+		context.Feeds = ssg.FeedLinks[feed.Slug]
+		outputDir := dir
+		if page.Paginator.Page > 1 {
+			outputDir = filepath.Join(dir, "page", strconv.Itoa(page.Paginator.Page))
+		}
+		jobs = append(jobs, RenderJob{
+			Template:   templatePath,
+			Context:    context,
+			OutputPath: filepath.Join(outputDir, "index.html"),
+			Key:        PageKey(ssg, templatePath, page),
+		})
+	}
+	return jobs
+}
@@ -0,0 +1,69 @@
+package plugins
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name     string
+		posts    int
+		pageSize int
+		// sizes are the numbers of posts of the pages
+		sizes []int
+	}{
+		{"no posts", 0, 10, []int{0}},
+		{"no page size", 25, 0, []int{25}},
+		{"no posts and no page size", 0, 0, []int{0}},
+		{"negative page size", 3, -1, []int{3}},
+		{"one partial page", 3, 10, []int{3}},
+		{"exactly one page", 10, 10, []int{10}},
+		{"last page partial", 25, 10, []int{10, 10, 5}},
+		{"last page full", 20, 10, []int{10, 10}},
+		{"one post per page", 3, 1, []int{1, 1, 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			feed := models.Feed{Slug: "posts"}
+			for i := range test.posts {
+				feed.Posts = append(feed.Posts, models.Post{Frontmatter: models.FrontMatter{Slug: fmt.Sprint(i)}})
+			}
+			pages := Paginate(feed, test.pageSize)
+			if len(pages) != len(test.sizes) {
+				t.Fatalf("Paginate() = %d pages, want %d", len(pages), len(test.sizes))
+			}
+			next := 0
+			for i, page := range pages {
+				number := i + 1
+				paginator := page.Paginator
+				if len(page.Feed.Posts) != test.sizes[i] {
+					t.Errorf("page %d has %d posts, want %d", number, len(page.Feed.Posts), test.sizes[i])
+				}
+				for _, post := range page.Feed.Posts {
+					if post.Frontmatter.Slug != fmt.Sprint(next) {
+						t.Errorf("page %d has post %s, want %d", number, post.Frontmatter.Slug, next)
+					}
+					next++
+				}
+				if paginator.Page != number || paginator.TotalPages != len(pages) || paginator.TotalPosts != test.posts {
+					t.Errorf("page %d paginator = %+v", number, paginator)
+				}
+				wantPrev, wantNext := "", ""
+				if number == 2 {
+					wantPrev = "posts"
+				} else if number > 2 {
+					wantPrev = fmt.Sprintf("posts/page/%d", number-1)
+				}
+				if number < len(pages) {
+					wantNext = fmt.Sprintf("posts/page/%d", number+1)
+				}
+				if paginator.PrevURL != wantPrev || paginator.NextURL != wantNext {
+					t.Errorf("page %d links to %q and %q, want %q and %q", number, paginator.PrevURL, paginator.NextURL, wantPrev, wantNext)
+				}
+			}
+		})
+	}
+}
@@ -2,13 +2,59 @@ package plugins
 
 import (
 	"reflect"
+	"sort"
 
 	"github.com/mr-destructive/mr-destructive.github.io/models"
 )
 
 type Plugin interface {
 	Name() string
-	Execute(ssg *models.SSG)
+	Execute(ssg *models.SSG) error
+}
+
+// Dependent is implemented by plugins that need other plugins, named as in
+// the plugins list of ssg.json, to have executed before them.
+type Dependent interface {
+	Requires() []string
+}
+
+// Phase is the step of the build in which a plugin executes.
+type Phase int
+
+const (
+	// PhaseRead plugins load the content, like readPosts.
+	PhaseRead Phase = iota
+	// PhaseRender plugins turn the content into pages, the default.
+	PhaseRender
+	// PhaseWrite plugins work on the pages already written, like Sitemap.
+	PhaseWrite
+)
+
+// Phased is implemented by plugins that do not execute in PhaseRender.
+type Phased interface {
+	Phase() Phase
+}
+
+// The hooks let a plugin act around each phase, in addition to Execute.
+// They run across all plugins in dependency order.
+type BeforeReadHook interface {
+	BeforeRead(ssg *models.SSG) error
+}
+
+type AfterReadHook interface {
+	AfterRead(ssg *models.SSG) error
+}
+
+type BeforeRenderHook interface {
+	BeforeRender(ssg *models.SSG) error
+}
+
+type AfterRenderHook interface {
+	AfterRender(ssg *models.SSG) error
+}
+
+type AfterWriteHook interface {
+	AfterWrite(ssg *models.SSG) error
 }
 
 var pluginRegistry = make(map[string]reflect.Type)
@@ -21,3 +67,13 @@ func GetPluginType(name string) (reflect.Type, bool) {
 	pluginType, exists := pluginRegistry[name]
 	return pluginType, exists
 }
+
+// PluginNames returns the names of the registered plugins, sorted.
+func PluginNames() []string {
+	names := make([]string, 0, len(pluginRegistry))
+	for name := range pluginRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,237 @@
+package plugins
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// RelatedPlugin finds the posts related to every published post, scored by the tags
+// and series they share and by the TF-IDF similarity of their markdown.
+// Its options set the number of related posts and the weight of each
+// score, a weight of 0 leaves that score out.
+type RelatedPlugin struct {
+	PluginName string `json:"-"`
+	// Count is the number of related posts of a post, 5 if zero
+	Count int `json:"count"`
+	// Tags weighs each shared tag, 1 if unset
+	Tags *float64 `json:"tags"`
+	// Series weighs each shared series, 2 if unset
+	Series *float64 `json:"series"`
+	// Content weighs the similarity of the markdown, from 0 to 1, 5 if
+	// unset
+	Content *float64 `json:"content"`
+}
+
+func (p *RelatedPlugin) Name() string {
+	return p.PluginName
+}
+
+func (p *RelatedPlugin) Requires() []string {
+	return []string{"readPosts"}
+}
+
+func (p *RelatedPlugin) Phase() Phase {
+	return PhaseRead
+}
+
+// relatedTerms is the number of the highest weighted words of a post kept
+// for the content similarity, enough to tell what a post is about.
+const relatedTerms = 64
+
+func (p *RelatedPlugin) Execute(ssg *models.SSG) error {
+	start := time.Now()
+	count := p.Count
+	if count <= 0 {
+		count = 5
+	}
+	tagWeight := weightOr(p.Tags, 1)
+	seriesWeight := weightOr(p.Series, 2)
+	contentWeight := weightOr(p.Content, 5)
+
+	// the posts without a page are neither related nor given related posts
+	posts := []models.Post{}
+	for _, post := range ssg.Posts {
+		if post.Frontmatter.Status == "draft" || post.Frontmatter.Date == "" {
+			continue
+		}
+		CleanPostFrontmatter(&post, ssg)
+		posts = append(posts, post)
+	}
+	series := make([][]string, len(posts))
+	for name, taxonomy := range ssg.Config.Taxonomies {
+		if taxonomy.OrderKey == "" {
+			continue
+		}
+		for i, post := range posts {
+			for _, term := range TermsOf(post, taxonomy.Key) {
+				series[i] = append(series[i], name+"/"+term)
+			}
+		}
+	}
+	var vectors []termVector
+	if contentWeight != 0 {
+		vectors = tfidf(posts)
+	}
+
+	type scored struct {
+		post  int
+		score float64
+	}
+	ssg.Related = make(map[string][]models.Post)
+	for i, post := range posts {
+		candidates := []scored{}
+		for j, other := range posts {
+			// the same post may be published under several paths
+			if i == j || other.Frontmatter.Slug == post.Frontmatter.Slug {
+				continue
+			}
+			score := tagWeight*float64(shared(post.Frontmatter.Tags, other.Frontmatter.Tags)) +
+				seriesWeight*float64(shared(series[i], series[j]))
+			if vectors != nil {
+				score += contentWeight * vectors[i].dot(vectors[j])
+			}
+			if score > 0 {
+				candidates = append(candidates, scored{post: j, score: score})
+			}
+		}
+		slices.SortStableFunc(candidates, func(a, b scored) int {
+			if a.score != b.score {
+				return cmp.Compare(b.score, a.score)
+			}
+			return strings.Compare(postDay(posts[b.post]), postDay(posts[a.post]))
+		})
+		related := []models.Post{}
+		seen := make(map[string]bool)
+		for _, candidate := range candidates {
+			other := posts[candidate.post]
+			if seen[other.Frontmatter.Slug] {
+				continue
+			}
+			seen[other.Frontmatter.Slug] = true
+			related = append(related, other)
+			if len(related) == count {
+				break
+			}
+		}
+		ssg.Related[post.SourcePath] = related
+	}
+	fmt.Printf("Related posts of %d posts in %v\n", len(posts), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func weightOr(weight *float64, fallback float64) float64 {
+	if weight == nil {
+		return fallback
+	}
+	return *weight
+}
+
+// shared returns the number of values in both a and b.
+func shared(a, b []string) int {
+	n := 0
+	for _, value := range a {
+		if slices.Contains(b, value) {
+			n++
+		}
+	}
+	return n
+}
+
+// termVector is a normalized TF-IDF vector, ordered by term.
+type termVector []termWeight
+
+type termWeight struct {
+	term   int
+	weight float64
+}
+
+// dot returns the cosine similarity of v and other.
+func (v termVector) dot(other termVector) float64 {
+	sum := 0.0
+	for i, j := 0, 0; i < len(v) && j < len(other); {
+		switch {
+		case v[i].term < other[j].term:
+			i++
+		case v[i].term > other[j].term:
+			j++
+		default:
+			sum += v[i].weight * other[j].weight
+			i++
+			j++
+		}
+	}
+	return sum
+}
+
+// tfidf returns the TF-IDF vector of the markdown of every post, keeping
+// the relatedTerms highest weighted words of each.
+func tfidf(posts []models.Post) []termVector {
+	ids := make(map[string]int)
+	counts := make([]map[int]int, len(posts))
+	documents := make(map[int]int)
+	for i, post := range posts {
+		counts[i] = make(map[int]int)
+		words := strings.FieldsFunc(strings.ToLower(post.Markdown), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			if len(word) < 3 {
+				continue
+			}
+			id, ok := ids[word]
+			if !ok {
+				id = len(ids)
+				ids[word] = id
+			}
+			if counts[i][id] == 0 {
+				documents[id]++
+			}
+			counts[i][id]++
+		}
+	}
+
+	vectors := make([]termVector, len(posts))
+	for i := range posts {
+		vector := termVector{}
+		for term, count := range counts[i] {
+			idf := math.Log(float64(len(posts)) / float64(documents[term]))
+			vector = append(vector, termWeight{term: term, weight: (1 + math.Log(float64(count))) * idf})
+		}
+		slices.SortFunc(vector, func(a, b termWeight) int {
+			if a.weight != b.weight {
+				return cmp.Compare(b.weight, a.weight)
+			}
+			return cmp.Compare(a.term, b.term)
+		})
+		vector = vector[:min(len(vector), relatedTerms)]
+		norm := 0.0
+		for _, term := range vector {
+			norm += term.weight * term.weight
+		}
+		norm = math.Sqrt(norm)
+		for j := range vector {
+			if norm > 0 {
+				vector[j].weight /= norm
+			}
+		}
+		slices.SortFunc(vector, func(a, b termWeight) int {
+			return cmp.Compare(a.term, b.term)
+		})
+		vectors[i] = vector
+	}
+	return vectors
+}
+
+func init() {
+	RegisterPlugin("Related", reflect.TypeOf(RelatedPlugin{
+		PluginName: "Related",
+	}))
+}
@@ -0,0 +1,148 @@
+package plugins
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+func relatedPost(title, date, status, markdown string, tags ...string) models.Post {
+	post := taxonomyPost(title, date, status, nil, tags...)
+	post.SourcePath = "posts/" + post.Frontmatter.Slug + ".md"
+	post.Markdown = markdown
+	return post
+}
+
+func TestTfidf(t *testing.T) {
+	many := []string{}
+	for i := range relatedTerms + 10 {
+		many = append(many, fmt.Sprintf("word%d", i))
+	}
+	vectors := tfidf([]models.Post{
+		{Markdown: "Goroutines and channels: goroutines talk over channels."},
+		{Markdown: "Channels carry values between goroutines"},
+		{Markdown: "Django templates render views"},
+		{Markdown: "Goroutines, then Django views"},
+		{Markdown: "Go is ok, a b c"},
+		{Markdown: strings.Join(many, " ")},
+	})
+	similarity := func(i, j int) float64 {
+		return vectors[i].dot(vectors[j])
+	}
+	for i, vector := range vectors[:4] {
+		if got := vector.dot(vector); math.Abs(got-1) > 1e-9 {
+			t.Errorf("vector %d has a norm of %v, want 1", i, got)
+		}
+	}
+	if !(similarity(0, 1) > similarity(0, 3) && similarity(0, 3) > 0) {
+		t.Errorf("similarities %v, %v, want the first above the second above 0", similarity(0, 1), similarity(0, 3))
+	}
+	if got := similarity(0, 2); got != 0 {
+		t.Errorf("similarity of posts sharing no word = %v, want 0", got)
+	}
+	if got := similarity(0, 1); got != similarity(1, 0) {
+		t.Errorf("similarity is not symmetric: %v and %v", got, similarity(1, 0))
+	}
+	if got := len(vectors[4]); got != 0 {
+		t.Errorf("post of words shorter than 3 letters has %d terms, want 0", got)
+	}
+	if got := len(vectors[5]); got != relatedTerms {
+		t.Errorf("post of %d words has %d terms, want %d", len(many), got, relatedTerms)
+	}
+	for i := 1; i < len(vectors[5]); i++ {
+		if vectors[5][i-1].term >= vectors[5][i].term {
+			t.Fatalf("terms are not ordered: %v", vectors[5])
+		}
+	}
+}
+
+func TestRelated(t *testing.T) {
+	weight := func(weight float64) *float64 {
+		return &weight
+	}
+	posts := []models.Post{
+		relatedPost("Go web", "2024-01-01", "", "", "go", "web"),
+		relatedPost("Go web again", "2023-01-01", "", "", "go", "web"),
+		relatedPost("Go", "2022-01-01", "", "", "go"),
+		relatedPost("Go newer", "2024-06-01", "", "", "go"),
+		relatedPost("Rust", "2024-01-01", "", "", "rust"),
+		relatedPost("Go web draft", "2024-01-01", "draft", "", "go", "web"),
+		relatedPost("Go web undated", "", "", "", "go", "web"),
+	}
+	// the same post published under another path is related only once
+	copied := posts[1]
+	copied.SourcePath = "posts/copy.md"
+	posts = append(posts, copied)
+
+	tests := []struct {
+		name   string
+		plugin RelatedPlugin
+		want   string
+	}{
+		{
+			"top two",
+			RelatedPlugin{Count: 2, Content: weight(0)},
+			"go-web: Go web again, Go newer\ngo-web-again: Go web, Go newer\ngo: Go newer, Go web\n" +
+				"go-newer: Go web, Go web again\nrust: \ncopy: Go web, Go newer",
+		},
+		{
+			"all",
+			RelatedPlugin{Content: weight(0)},
+			"go-web: Go web again, Go newer, Go\ngo-web-again: Go web, Go newer, Go\n" +
+				"go: Go newer, Go web, Go web again\ngo-newer: Go web, Go web again, Go\nrust: \n" +
+				"copy: Go web, Go newer, Go",
+		},
+		{
+			"tags left out",
+			RelatedPlugin{Tags: weight(0), Content: weight(0)},
+			"go-web: \ngo-web-again: \ngo: \ngo-newer: \nrust: \ncopy: ",
+		},
+	}
+	for _, test := range tests {
+		ssg := &models.SSG{Posts: posts}
+		if err := test.plugin.Execute(ssg); err != nil {
+			t.Fatal(err)
+		}
+		lines := []string{}
+		for _, post := range posts {
+			related, ok := ssg.Related[post.SourcePath]
+			if !ok {
+				continue
+			}
+			titles := []string{}
+			for _, other := range related {
+				titles = append(titles, other.Frontmatter.Title)
+			}
+			name := strings.TrimSuffix(strings.TrimPrefix(post.SourcePath, "posts/"), ".md")
+			lines = append(lines, name+": "+strings.Join(titles, ", "))
+		}
+		if got := strings.Join(lines, "\n"); got != test.want {
+			t.Errorf("%s: related posts =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRelatedContent(t *testing.T) {
+	ssg := &models.SSG{Posts: []models.Post{
+		relatedPost("Channels", "2024-01-01", "", "Goroutines send values over channels"),
+		relatedPost("Goroutines", "2023-01-01", "", "Goroutines and channels, more goroutines"),
+		relatedPost("Django", "2024-02-01", "", "Django views render templates"),
+		relatedPost("Draft", "2024-03-01", "draft", "Goroutines send values over channels"),
+	}}
+	plugin := RelatedPlugin{}
+	if err := plugin.Execute(ssg); err != nil {
+		t.Fatal(err)
+	}
+	if got := ssg.Related["posts/channels.md"]; len(got) != 1 || got[0].Frontmatter.Title != "Goroutines" {
+		t.Errorf("posts related to Channels = %v, want Goroutines", got)
+	}
+	if got := ssg.Related["posts/django.md"]; len(got) != 0 {
+		t.Errorf("posts related to Django = %v, want none", got)
+	}
+	if _, ok := ssg.Related["posts/draft.md"]; ok {
+		t.Errorf("draft has related posts")
+	}
+}
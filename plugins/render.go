@@ -0,0 +1,162 @@
+package plugins
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/mr-destructive/mr-destructive.github.io/assets"
+	"github.com/mr-destructive/mr-destructive.github.io/minify"
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// RenderJob is a single page: Template executed with Context and written
+// to OutputPath. Key is the build cache key of the page, see PostKey and
+// FeedKey.
+type RenderJob struct {
+	Template   string
+	Context    models.TemplateContext
+	OutputPath string
+	Key        string
+}
+
+// TemplateFuncs are the functions the templates of the templates dir can
+// call, on top of the builtin ones.
+var TemplateFuncs = template.FuncMap{
+	// terms returns the terms of a post for a front matter key, like
+	// {{ range terms "series" .Post }}
+	"terms": func(key string, post models.Post) []string {
+		return TermsOf(post, key)
+	},
+	"slugify": Slugify,
+}
+
+func init() {
+	maps.Copy(TemplateFuncs, AssetFuncs(nil, models.BlogConfig{}))
+}
+
+// AssetFuncs returns the template functions linking to the files of the
+// static dir: asset returns the URL of the fingerprinted copy of a file,
+// like {{ asset "style.css" }}, and integrity its Subresource Integrity
+// hash, empty when there is none. Without a pipeline asset returns the
+// URL of the file itself.
+func AssetFuncs(pipeline *assets.Pipeline, blog models.BlogConfig) template.FuncMap {
+	return template.FuncMap{
+		"asset": func(name string) (string, error) {
+			if pipeline == nil {
+				return "/" + blog.PrefixURL + strings.TrimPrefix(name, "/"), nil
+			}
+			asset, ok := pipeline.Lookup(name)
+			if !ok {
+				return "", fmt.Errorf("no file %q in the static dir", name)
+			}
+			return "/" + blog.PrefixURL + asset.URL, nil
+		},
+		"integrity": func(name string) string {
+			asset, _ := pipeline.Lookup(name)
+			return asset.Integrity
+		},
+	}
+}
+
+// Workers returns the number of pages rendered at once, taken from the
+// "build" section of the config and defaulting to the number of CPUs.
+func Workers(ssg *models.SSG) int {
+	if ssg.Config.Build.Workers > 0 {
+		return ssg.Config.Build.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// WritePage writes the HTML page output, minified when the config asks for
+// it outside of the dev server.
+func WritePage(ssg *models.SSG, output string, html []byte) error {
+	if ssg.Config.Minify.HTML && !ssg.Config.Dev {
+		minified := minify.HTML(html)
+		ssg.Minified.Add(len(html), len(minified))
+		html = minified
+	}
+	err := os.MkdirAll(filepath.Dir(output), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, html, 0660)
+}
+
+// MinifyAsset returns the transform of the static files minifying the
+// stylesheets and the scripts when config asks for it outside of the dev
+// server.
+func MinifyAsset(config models.SSG_CONFIG) assets.Transform {
+	return func(name string, data []byte) []byte {
+		switch {
+		case config.Dev:
+			return data
+		case config.Minify.CSS && path.Ext(name) == ".css":
+			return minify.CSS(data)
+		case config.Minify.JS && path.Ext(name) == ".js":
+			return minify.JS(data)
+		}
+		return data
+	}
+}
+
+// RenderPages renders jobs concurrently with ssg.TemplateFS. When several
+// jobs write the same path only the last one is rendered, so the output is
+// the same as rendering the jobs one after another. Jobs only read from
+// ssg, the build cache is the one piece of shared state they update and it
+// does its own locking. Every failed job is reported in the returned error.
+func RenderPages(ssg *models.SSG, jobs []RenderJob) error {
+	last := make(map[string]int)
+	for i, job := range jobs {
+		last[filepath.Clean(job.OutputPath)] = i
+	}
+
+	errs := make([]error, len(jobs))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for range min(Workers(ssg), len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				errs[i] = renderPage(ssg, jobs[i])
+			}
+		}()
+	}
+	for i, job := range jobs {
+		if last[filepath.Clean(job.OutputPath)] == i {
+			work <- i
+		}
+	}
+	close(work)
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func renderPage(ssg *models.SSG, job RenderJob) error {
+	if ssg.Cache.Fresh(job.OutputPath, job.Key) {
+		ssg.Manifest.Add(PageManifest(job.OutputPath, job.Context))
+		return nil
+	}
+	source := job.Context.Post.SourcePath
+	buffer := bytes.Buffer{}
+	err := ssg.TemplateFS.ExecuteTemplate(&buffer, job.Template, job.Context)
+	if err != nil {
+		return PageError(source, job.OutputPath, err)
+	}
+	err = WritePage(ssg, job.OutputPath, buffer.Bytes())
+	if err != nil {
+		return PageError(source, job.OutputPath, err)
+	}
+	ssg.Cache.Record(job.OutputPath, job.Key)
+	ssg.Manifest.Add(PageManifest(job.OutputPath, job.Context))
+	return nil
+}
@@ -0,0 +1,96 @@
+package plugins
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/cache"
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// renderTree renders jobs with workers into a new output dir, and returns
+// the files it holds by path and the pages of the manifest.
+func renderTree(t *testing.T, workers int, jobs func(output string) []RenderJob) (map[string]string, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	output := filepath.Join(dir, "public")
+	buildCache, err := cache.Open(filepath.Join(dir, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := template.Must(template.New("").Funcs(TemplateFuncs).Parse(
+		`{{ define "post.html" }}<h1>{{ .Post.Frontmatter.Title }}</h1>{{ range terms "tags" .Post }}<a>{{ . }}</a>{{ end }}{{ .Post.Content }}{{ end }}`))
+	ssg := &models.SSG{TemplateFS: templates, Cache: buildCache, Manifest: &models.Manifest{}}
+	ssg.Config.Build.Workers = workers
+	if err := RenderPages(ssg, jobs(output)); err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	err = filepath.WalkDir(output, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		rel, _ := filepath.Rel(output, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := []string{}
+	for _, page := range ssg.Manifest.Pages(output) {
+		rel, _ := filepath.Rel(output, page.Output)
+		pages = append(pages, filepath.ToSlash(rel))
+	}
+	return files, pages
+}
+
+func TestRenderPagesParallel(t *testing.T) {
+	jobs := func(output string) []RenderJob {
+		jobs := []RenderJob{}
+		for i := range 200 {
+			post := models.Post{Content: template.HTML(fmt.Sprintf("<p>Post %d</p>", i))}
+			post.Frontmatter.Title = fmt.Sprintf("Post %d", i)
+			post.Frontmatter.Tags = []string{"go", fmt.Sprint(i % 7)}
+			// every tenth post is written again to the path of the post
+			// before it, the later job wins
+			slug := fmt.Sprintf("post-%d", i)
+			if i%10 == 0 && i > 0 {
+				slug = fmt.Sprintf("post-%d", i-1)
+			}
+			jobs = append(jobs, RenderJob{
+				Template:   "post.html",
+				Context:    models.TemplateContext{Post: post},
+				OutputPath: filepath.Join(output, "posts", slug, "index.html"),
+				Key:        cache.Hash(fmt.Sprint(i)),
+			})
+		}
+		return jobs
+	}
+	serial, serialPages := renderTree(t, 1, jobs)
+	if got := serial["posts/post-9/index.html"]; got != "<h1>Post 10</h1><a>go</a><a>3</a><p>Post 10</p>" {
+		t.Errorf("post-9 = %q, want the later post written to its path", got)
+	}
+	for _, workers := range []int{2, 8, 64} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			parallel, parallelPages := renderTree(t, workers, jobs)
+			if !maps.Equal(parallel, serial) {
+				for path, data := range serial {
+					if parallel[path] != data {
+						t.Errorf("%s = %q, want %q as in a serial build", path, parallel[path], data)
+					}
+				}
+				t.Fatalf("the output of %d files differs from the %d of a serial build", len(parallel), len(serial))
+			}
+			if fmt.Sprint(parallelPages) != fmt.Sprint(serialPages) {
+				t.Errorf("manifest pages = %v, want %v", parallelPages, serialPages)
+			}
+		})
+	}
+}
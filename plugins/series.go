@@ -1,83 +1,49 @@
 package plugins
 
 import (
-	"bytes"
-	"fmt"
-	"log"
-	"os"
-	"path/filepath"
-	"reflect"
+	"sort"
 
 	"github.com/mr-destructive/mr-destructive.github.io/models"
 )
 
-type SeriesPlugin struct {
-	PluginName string
+// BeforeRender resolves the series of the posts before their pages are
+// rendered, so that each page can link to the other parts.
+func (p *TaxonomiesPlugin) BeforeRender(ssg *models.SSG) error {
+	ssg.Series = ResolveSeries(ssg)
+	return nil
 }
 
-func (p *SeriesPlugin) Name() string {
-	return p.PluginName
-}
-
-func (p *SeriesPlugin) Execute(ssg *models.SSG) {
-	config := &ssg.Config
-	seriesPost := make(map[string][]models.Post)
-	for _, post := range ssg.Posts {
-		if post.Frontmatter.Status == "draft" {
-			continue
-		}
-		CleanPostFrontmatter(&post, ssg)
-		if post.Frontmatter.Extras == nil {
-			continue
-		}
-		if post.Frontmatter.Extras["series"] == nil {
-			continue
-		}
-		seriesList := post.Frontmatter.Extras["series"].([]interface{})
-		for _, series := range seriesList {
-			series := Slugify(series.(string))
-			seriesPost[series] = append(seriesPost[series], post)
+// ResolveSeries returns the series every published post is part of, by
+// source path: the terms of the taxonomies with an order key.
+func ResolveSeries(ssg *models.SSG) map[string][]models.Series {
+	names := []string{}
+	for name, taxonomy := range ssg.Config.Taxonomies {
+		if taxonomy.OrderKey != "" {
+			names = append(names, name)
 		}
 	}
-	fmt.Println("seriesPost", len(seriesPost))
-
-	for series, posts := range seriesPost {
-		buffer := bytes.Buffer{}
-		templatePath := config.Blog.PagesConfig["series"].TemplatePath
-		if templatePath == "" {
-			templatePath = config.Blog.DefaultFeedTemplate
+	sort.Strings(names)
+	series := make(map[string][]models.Series)
+	for _, name := range names {
+		for _, feed := range Terms(ssg, ssg.Config.Taxonomies[name]) {
+			for i, post := range feed.Posts {
+				part := models.Series{
+					Taxonomy: name,
+					Name:     feed.Title,
+					URL:      feed.Slug,
+					Part:     i + 1,
+					Total:    len(feed.Posts),
+					Posts:    feed.Posts,
+				}
+				if i > 0 {
+					part.Prev = &feed.Posts[i-1]
+				}
+				if i < len(feed.Posts)-1 {
+					part.Next = &feed.Posts[i+1]
+				}
+				series[post.SourcePath] = append(series[post.SourcePath], part)
+			}
 		}
-		feed := models.Feed{
-			Title: series,
-			Type:  series,
-			Slug:  ssg.Config.Blog.PrefixURL + "series/" + series,
-			Posts: posts,
-		}
-
-		context := models.TemplateContext{
-			FeedPosts: []models.Feed{feed},
-			Themes: models.ThemeCombo{
-				Default:   config.Blog.Themes["default"],
-				Secondary: config.Blog.Themes["secondary"],
-			},
-			FeedInfo: feed,
-			Config: models.SSG_CONFIG{
-				Blog: config.Blog,
-			},
-		}
-		err := ssg.TemplateFS.ExecuteTemplate(&buffer, templatePath, context)
-		if err != nil {
-			log.Fatal(err)
-		}
-		feedPath := filepath.Join(".", config.Blog.OutputDir, "series", feed.Type)
-		err = os.MkdirAll(feedPath, os.ModePerm)
-		outputFeedPath := fmt.Sprintf("%s/index.html", feedPath)
-		err = os.WriteFile(outputFeedPath, buffer.Bytes(), 0660)
 	}
-}
-
-func init() {
-	RegisterPlugin("Series", reflect.TypeOf(SeriesPlugin{
-		PluginName: "Series",
-	}))
+	return series
 }
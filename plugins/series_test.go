@@ -0,0 +1,78 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+func TestResolveSeries(t *testing.T) {
+	part := func(title, date, status string, extras map[string]any) models.Post {
+		post := taxonomyPost(title, date, status, extras)
+		post.SourcePath = "posts/" + post.Frontmatter.Slug + ".md"
+		return post
+	}
+	go101 := func(order any) map[string]any {
+		return map[string]any{"series": "Go 101", "series_order": order}
+	}
+	ssg := &models.SSG{Posts: []models.Post{
+		part("Unnumbered late", "2024-04-01", "", go101(nil)),
+		part("Third", "2023-01-01", "", go101("3")),
+		part("First", "2024-01-01", "", go101(1)),
+		part("Unnumbered early", "2022-01-01", "", map[string]any{"series": []any{"Go 101", "Tools"}}),
+		part("Second", "2023-06-01", "", go101(2.0)),
+		part("Draft", "2021-01-01", "draft", go101(0)),
+		part("Alone", "2024-01-01", "", nil),
+	}}
+	ssg.Config.Taxonomies = map[string]models.Taxonomy{
+		"series": {Key: "series", Path: "series/{term}", OrderKey: "series_order", Slugify: true},
+		"tags":   {Key: "tags", Path: "tags/{term}"},
+	}
+	title := func(post *models.Post) string {
+		if post == nil {
+			return "-"
+		}
+		return post.Frontmatter.Title
+	}
+	describe := func(parts []models.Series) string {
+		lines := []string{}
+		for _, part := range parts {
+			titles := []string{}
+			for _, post := range part.Posts {
+				titles = append(titles, post.Frontmatter.Title)
+			}
+			lines = append(lines, fmt.Sprintf("%s %s %s %d/%d prev %s next %s [%s]",
+				part.Taxonomy, part.Name, part.URL, part.Part, part.Total,
+				title(part.Prev), title(part.Next), strings.Join(titles, ", ")))
+		}
+		return strings.Join(lines, "\n")
+	}
+	parts := "First, Second, Third, Unnumbered early, Unnumbered late"
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"posts/first.md", "series Go 101 series/go-101 1/5 prev - next Second [" + parts + "]"},
+		{"posts/second.md", "series Go 101 series/go-101 2/5 prev First next Third [" + parts + "]"},
+		{"posts/third.md", "series Go 101 series/go-101 3/5 prev Second next Unnumbered early [" + parts + "]"},
+		{
+			"posts/unnumbered-early.md",
+			"series Go 101 series/go-101 4/5 prev Third next Unnumbered late [" + parts + "]\n" +
+				"series Tools series/tools 1/1 prev - next - [Unnumbered early]",
+		},
+		{"posts/unnumbered-late.md", "series Go 101 series/go-101 5/5 prev Unnumbered early next - [" + parts + "]"},
+		{"posts/draft.md", ""},
+		{"posts/alone.md", ""},
+	}
+	series := ResolveSeries(ssg)
+	for _, test := range tests {
+		if got := describe(series[test.source]); got != test.want {
+			t.Errorf("ResolveSeries()[%q] =\n%s\nwant\n%s", test.source, got, test.want)
+		}
+	}
+	if len(series) != 5 {
+		t.Errorf("ResolveSeries() has %d posts, want 5", len(series))
+	}
+}
@@ -3,75 +3,229 @@ package plugins
 import (
 	"encoding/xml"
 	"fmt"
-	"log"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/mr-destructive/mr-destructive.github.io/models"
 )
 
+// maxSitemapURLs is the most URLs a sitemap may list, past it the URLs are
+// split into sitemaps listed by a sitemap index.
+const maxSitemapURLs = 50000
+
 type URL struct {
-	Loc        string `xml:"loc"`
-	LastMod    string `xml:"lastmod,omitempty"`
-	ChangeFreq string `xml:"changefreq,omitempty"`
-	Priority   string `xml:"priority,omitempty"`
+	Loc        string  `xml:"loc"`
+	LastMod    string  `xml:"lastmod,omitempty"`
+	ChangeFreq string  `xml:"changefreq,omitempty"`
+	Priority   string  `xml:"priority,omitempty"`
+	Images     []Image `xml:"image:image"`
+}
+
+type Image struct {
+	Loc string `xml:"image:loc"`
 }
 
 type Sitemap struct {
-	XMLName xml.Name `xml:"urlset"`
-	Xmlns   string   `xml:"xmlns,attr"`
-	URLs    []URL    `xml:"url"`
+	XMLName    xml.Name `xml:"urlset"`
+	Xmlns      string   `xml:"xmlns,attr"`
+	XmlnsImage string   `xml:"xmlns:image,attr"`
+	URLs       []URL    `xml:"url"`
 }
 
+type SitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []SitemapRef `xml:"sitemap"`
+}
+
+type SitemapRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// SitemapPlugin writes sitemap.xml with every page of the build manifest,
+// and a robots.txt pointing to it. Its options set the changefreq and
+// priority of every URL, and MaxURLs the number of URLs past which the
+// sitemap becomes an index of sitemap-1.xml, sitemap-2.xml and so on.
 type SitemapPlugin struct {
-	PluginName string
+	PluginName string `json:"-"`
+	ChangeFreq string `json:"changefreq"`
+	Priority   string `json:"priority"`
+	MaxURLs    int    `json:"max_urls"`
 }
 
 func (s *SitemapPlugin) Name() string {
 	return s.PluginName
 }
 
-func (s *SitemapPlugin) Execute(ssg *models.SSG) {
+func (s *SitemapPlugin) Requires() []string {
+	return []string{"readPosts"}
+}
+
+func (s *SitemapPlugin) Phase() Phase {
+	return PhaseWrite
+}
+
+// PageManifest returns the manifest entry of the page written to output
+// with context: a post page last changed with its post, a page listing
+// posts with the newest of them.
+func PageManifest(output string, context models.TemplateContext) models.ManifestPage {
+	page := models.ManifestPage{Output: output}
+	if context.Post.Frontmatter.Date != "" {
+		page.LastMod = lastMod(context.Post)
+		if context.Post.Frontmatter.ImageUrl != "" {
+			page.Images = []string{context.Post.Frontmatter.ImageUrl}
+		}
+		return page
+	}
+	for _, feed := range append([]models.Feed{context.FeedInfo}, context.FeedPosts...) {
+		for _, post := range feed.Posts {
+			page.LastMod = max(page.LastMod, lastMod(post))
+		}
+	}
+	return page
+}
+
+// lastMod returns the day post last changed, empty when its date is not
+// a day.
+func lastMod(post models.Post) string {
+	day, ok := parseDay(post.Frontmatter.Date)
+	if !ok {
+		return ""
+	}
+	if updated, ok := lastModified(post); ok && updated.After(day) {
+		day = updated
+	}
+	return day.Format(time.DateOnly)
+}
+
+// pageURL returns the absolute URL of a page of the output dir, the URL of
+// its directory for an index.html.
+func pageURL(blog models.BlogConfig, output string) (string, error) {
+	rel, err := filepath.Rel(blog.OutputDir, output)
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if path.Base(rel) == "index.html" {
+		rel = strings.TrimSuffix(strings.TrimSuffix(rel, "index.html"), "/")
+	}
+	return AbsoluteURL(blog, blog.PrefixURL+rel), nil
+}
+
+func (s *SitemapPlugin) Execute(ssg *models.SSG) error {
 	config := &ssg.Config
-	baseURL := config.Blog.BaseUrl
-	prefixURL := config.Blog.PrefixURL
-	outputDir := config.Blog.OutputDir
-
-	var urls []URL
-	for _, post := range ssg.Posts {
-		if post.Frontmatter.Status == "draft" {
-			continue
+	// the admin copy is not for search engines
+	if config.AdminMode {
+		return nil
+	}
+	blog := config.Blog
+	changeFreq := s.ChangeFreq
+	if changeFreq == "" {
+		changeFreq = "weekly"
+	}
+	priority := s.Priority
+	if priority == "" {
+		priority = "0.8"
+	}
+	maxURLs := s.MaxURLs
+	if maxURLs <= 0 || maxURLs > maxSitemapURLs {
+		maxURLs = maxSitemapURLs
+	}
+
+	urls := []URL{}
+	for _, page := range ssg.Manifest.Pages(blog.OutputDir) {
+		loc, err := pageURL(blog, page.Output)
+		if err != nil {
+			return PageError("", page.Output, err)
+		}
+		base, err := url.Parse(loc)
+		if err != nil {
+			return PageError("", page.Output, err)
 		}
-		pageURL := fmt.Sprintf("%s/%s%s/%s", baseURL, prefixURL, post.Frontmatter.Type, post.Frontmatter.Slug)
-		lastMod := post.Frontmatter.Date
-		if lastMod == "" {
-			lastMod = time.Now().Format("2006-01-02")
+		sitemapURL := URL{
+			Loc:        loc,
+			LastMod:    page.LastMod,
+			ChangeFreq: changeFreq,
+			Priority:   priority,
 		}
-		urls = append(urls, URL{
-			Loc:        pageURL,
-			LastMod:    lastMod,
-			ChangeFreq: "weekly",
-			Priority:   "0.8",
-		})
+		for _, image := range page.Images {
+			ref, err := url.Parse(image)
+			if err != nil {
+				return PageError("", page.Output, fmt.Errorf("image %q: %w", image, err))
+			}
+			sitemapURL.Images = append(sitemapURL.Images, Image{Loc: base.ResolveReference(ref).String()})
+		}
+		urls = append(urls, sitemapURL)
 	}
 
-	sitemap := Sitemap{
-		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
-		URLs:  urls,
+	sitemapPath := filepath.Join(blog.OutputDir, "sitemap.xml")
+	if len(urls) <= maxURLs {
+		if err := s.writeSitemap(ssg, sitemapPath, urls); err != nil {
+			return err
+		}
+	} else {
+		index := SitemapIndex{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+		for start := 0; start < len(urls); start += maxURLs {
+			part := urls[start:min(start+maxURLs, len(urls))]
+			name := fmt.Sprintf("sitemap-%d.xml", start/maxURLs+1)
+			if err := s.writeSitemap(ssg, filepath.Join(blog.OutputDir, name), part); err != nil {
+				return err
+			}
+			ref := SitemapRef{Loc: AbsoluteURL(blog, blog.PrefixURL+name)}
+			for _, partURL := range part {
+				ref.LastMod = max(ref.LastMod, partURL.LastMod)
+			}
+			index.Sitemaps = append(index.Sitemaps, ref)
+		}
+		if err := writeXML(ssg, sitemapPath, index); err != nil {
+			return err
+		}
 	}
-	xmlData, err := xml.MarshalIndent(sitemap, "", "  ")
+	fmt.Println("Sitemap generated at", sitemapPath, "with", len(urls), "URLs")
+	return writeRobots(ssg, AbsoluteURL(blog, blog.PrefixURL+"sitemap.xml"))
+}
+
+func (s *SitemapPlugin) writeSitemap(ssg *models.SSG, output string, urls []URL) error {
+	return writeXML(ssg, output, Sitemap{
+		Xmlns:      "http://www.sitemaps.org/schemas/sitemap/0.9",
+		XmlnsImage: "http://www.google.com/schemas/sitemap-image/1.1",
+		URLs:       urls,
+	})
+}
+
+func writeXML(ssg *models.SSG, output string, v any) error {
+	xmlData, err := xml.MarshalIndent(v, "", "  ")
 	if err != nil {
-		log.Fatal("Error generating XML:", err)
+		return PageError("", output, fmt.Errorf("generating XML: %w", err))
 	}
+	_, err = WriteOutput(ssg, output, []byte(xml.Header+string(xmlData)+"\n"))
+	return err
+}
 
-	sitemapPath := filepath.Join(outputDir, "sitemap.xml")
-	err = os.WriteFile(sitemapPath, []byte(xml.Header+string(xmlData)), 0644)
+// writeRobots writes robots.txt with the sitemap, keeping the rules of a
+// robots.txt of the static dir or allowing everything but the admin copy.
+func writeRobots(ssg *models.SSG, sitemapURL string) error {
+	blog := ssg.Config.Blog
+	rules, err := os.ReadFile(filepath.Join(blog.StaticDir, "robots.txt"))
+	if os.IsNotExist(err) {
+		rules = fmt.Appendf(nil, "User-agent: *\nAllow: /\nDisallow: /%s%s/\n", blog.PrefixURL, blog.AdminDir)
+	} else if err != nil {
+		return err
+	}
+	robots := strings.TrimRight(string(rules), "\n") + "\n\nSitemap: " + sitemapURL + "\n"
+	// written every time, copyStaticFiles may have replaced it
+	output := filepath.Join(blog.OutputDir, "robots.txt")
+	err = os.WriteFile(output, []byte(robots), 0660)
 	if err != nil {
-		log.Fatal("Error writing sitemap.xml:", err)
+		return PageError("", output, err)
 	}
-	fmt.Println("Sitemap generated at", sitemapPath)
+	return nil
 }
 
 func init() {
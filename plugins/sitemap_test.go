@@ -0,0 +1,125 @@
+package plugins
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+func TestSitemap(t *testing.T) {
+	tests := []struct {
+		name    string
+		pages   int
+		maxURLs int
+		// parts are the numbers of URLs of each sitemap, a single one is
+		// sitemap.xml and several are listed by an index
+		parts []int
+	}{
+		{"no pages", 0, 0, []int{0}},
+		{"under the limit", 3, 0, []int{3}},
+		{"at the limit", 3, 3, []int{3}},
+		{"over the limit", 5, 2, []int{2, 2, 1}},
+		{"limit past the protocol maximum", 3, maxSitemapURLs + 1, []int{3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			output := filepath.Join(dir, "public")
+			ssg := &models.SSG{Manifest: &models.Manifest{}}
+			ssg.Config.Blog = models.BlogConfig{
+				BaseUrl:   "https://example.com",
+				PrefixURL: "blog/",
+				OutputDir: output,
+				StaticDir: filepath.Join(dir, "static"),
+				AdminDir:  "admin",
+			}
+			days := []string{"2024-01-01", "2024-03-01", "2024-02-01", "2024-05-01", "2024-04-01"}
+			for i := range test.pages {
+				ssg.Manifest.Add(models.ManifestPage{
+					Output:  filepath.Join(output, "posts", string(rune('a'+i)), "index.html"),
+					LastMod: days[i],
+				})
+			}
+			// pages of the admin copy are left out
+			ssg.Manifest.Add(models.ManifestPage{Output: filepath.Join(dir, "admin", "index.html")})
+
+			plugin := &SitemapPlugin{MaxURLs: test.maxURLs}
+			if err := plugin.Execute(ssg); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(output, "sitemap.xml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sitemaps [][]byte
+			if len(test.parts) == 1 {
+				sitemaps = [][]byte{data}
+			} else {
+				var index SitemapIndex
+				if err := xml.Unmarshal(data, &index); err != nil || index.XMLName.Local != "sitemapindex" {
+					t.Fatalf("sitemap.xml is not an index: %v\n%s", err, data)
+				}
+				if len(index.Sitemaps) != len(test.parts) {
+					t.Fatalf("the index lists %d sitemaps, want %d", len(index.Sitemaps), len(test.parts))
+				}
+				for i, ref := range index.Sitemaps {
+					name := "sitemap-" + string(rune('1'+i)) + ".xml"
+					if ref.Loc != "https://example.com/blog/"+name {
+						t.Errorf("sitemap %d is at %s", i+1, ref.Loc)
+					}
+					part, err := os.ReadFile(filepath.Join(output, name))
+					if err != nil {
+						t.Fatal(err)
+					}
+					sitemaps = append(sitemaps, part)
+				}
+				if index.Sitemaps[1].LastMod != "2024-05-01" {
+					t.Errorf("sitemap 2 lastmod = %s, want the newest of its pages", index.Sitemaps[1].LastMod)
+				}
+			}
+			next := 0
+			for i, data := range sitemaps {
+				var sitemap Sitemap
+				if err := xml.Unmarshal(data, &sitemap); err != nil {
+					t.Fatalf("sitemap %d is not XML: %v", i+1, err)
+				}
+				if len(sitemap.URLs) != test.parts[i] {
+					t.Errorf("sitemap %d lists %d URLs, want %d", i+1, len(sitemap.URLs), test.parts[i])
+				}
+				for _, u := range sitemap.URLs {
+					want := "https://example.com/blog/posts/" + string(rune('a'+next))
+					if u.Loc != want || u.LastMod != days[next] {
+						t.Errorf("URL %d = %s %s, want %s %s", next, u.Loc, u.LastMod, want, days[next])
+					}
+					next++
+				}
+			}
+
+			robots, err := os.ReadFile(filepath.Join(output, "robots.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(robots), "Disallow: /blog/admin/\n") || !strings.HasSuffix(string(robots), "Sitemap: https://example.com/blog/sitemap.xml\n") {
+				t.Errorf("robots.txt =\n%s", robots)
+			}
+		})
+	}
+}
+
+func TestSitemapAdminMode(t *testing.T) {
+	output := t.TempDir()
+	ssg := &models.SSG{Manifest: &models.Manifest{}}
+	ssg.Config.AdminMode = true
+	ssg.Config.Blog = models.BlogConfig{BaseUrl: "https://example.com", OutputDir: output}
+	if err := (&SitemapPlugin{}).Execute(ssg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(output, "sitemap.xml")); !os.IsNotExist(err) {
+		t.Errorf("the admin copy has a sitemap: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package plugins
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mr-destructive/mr-destructive.github.io/cache"
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// TaxonomiesPlugin renders the pages of every taxonomy of the config: a
+// page per term listing its posts, and the index page of the terms.
+type TaxonomiesPlugin struct {
+	PluginName string `json:"-"`
+}
+
+func (p *TaxonomiesPlugin) Name() string {
+	return p.PluginName
+}
+
+func (p *TaxonomiesPlugin) Requires() []string {
+	return []string{"renderTemplates"}
+}
+
+func (p *TaxonomiesPlugin) Execute(ssg *models.SSG) error {
+	names := []string{}
+	for name := range ssg.Config.Taxonomies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	jobs := []RenderJob{}
+	for _, name := range names {
+		jobs = append(jobs, taxonomyJobs(ssg, name, ssg.Config.Taxonomies[name])...)
+	}
+	return RenderPages(ssg, jobs)
+}
+
+// Terms groups the published posts by their terms of taxonomy, a feed per
+// term in the order of taxonomy.TermSort. The Slug of a feed is the URL of
+// the term page and its posts are in the order of taxonomy.Sort.
+func Terms(ssg *models.SSG, taxonomy models.Taxonomy) []models.Feed {
+	feeds := []models.Feed{}
+	index := make(map[string]int)
+	for _, post := range ssg.Posts {
+		if post.Frontmatter.Status == "draft" {
+			continue
+		}
+		CleanPostFrontmatter(&post, ssg)
+		seen := make(map[string]bool)
+		for _, term := range TermsOf(post, taxonomy.Key) {
+			segment := termSegment(term, taxonomy.Slugify)
+			if segment == "" || seen[segment] {
+				continue
+			}
+			seen[segment] = true
+			i, ok := index[segment]
+			if !ok {
+				i = len(feeds)
+				index[segment] = i
+				feeds = append(feeds, models.Feed{
+					Title: term,
+					Type:  term,
+					Slug:  ssg.Config.Blog.PrefixURL + strings.ReplaceAll(taxonomy.Path, "{term}", segment),
+				})
+			}
+			feeds[i].Posts = append(feeds[i].Posts, post)
+		}
+	}
+	for _, feed := range feeds {
+		sortTermPosts(feed.Posts, taxonomy)
+	}
+	slices.SortStableFunc(feeds, func(a, b models.Feed) int {
+		switch taxonomy.TermSort {
+		case "name_desc":
+			return strings.Compare(b.Title, a.Title)
+		case "count":
+			if len(a.Posts) != len(b.Posts) {
+				return len(b.Posts) - len(a.Posts)
+			}
+		}
+		return strings.Compare(a.Title, b.Title)
+	})
+	return feeds
+}
+
+// TermsOf returns the terms of post for the front matter key, which may
+// hold a single value or a list.
+func TermsOf(post models.Post, key string) []string {
+	frontmatter := post.Frontmatter
+	switch key {
+	case "tags":
+		return termValues(frontmatter.Tags)
+	case "type":
+		return termValues(frontmatter.Type)
+	case "status":
+		return termValues(frontmatter.Status)
+	case "year":
+		date, err := time.Parse("2006-01-02", frontmatter.Date[:min(len(frontmatter.Date), 10)])
+		if err != nil {
+			return nil
+		}
+		return []string{date.Format("2006")}
+	}
+	return termValues(frontmatter.Extras[key])
+}
+
+func termValues(value any) []string {
+	terms := []string{}
+	switch value := value.(type) {
+	case nil:
+	case string:
+		if term := strings.TrimSpace(value); term != "" {
+			terms = append(terms, term)
+		}
+	case []string:
+		for _, item := range value {
+			terms = append(terms, termValues(item)...)
+		}
+	case []any:
+		for _, item := range value {
+			terms = append(terms, termValues(item)...)
+		}
+	default:
+		terms = append(terms, fmt.Sprint(value))
+	}
+	return terms
+}
+
+// termSegment returns the part of the URL of a term page standing for
+// term, a slug when asked for or when term cannot be a directory name.
+func termSegment(term string, slugify bool) string {
+	if slugify || strings.ContainsAny(term, `/\`) || term == "." || term == ".." {
+		return Slugify(term)
+	}
+	return term
+}
+
+// sortTermPosts orders the posts of a term by taxonomy.Sort, or as parts
+// when the taxonomy has an order key.
+func sortTermPosts(posts []models.Post, taxonomy models.Taxonomy) {
+	slices.SortStableFunc(posts, func(a, b models.Post) int {
+		if taxonomy.OrderKey != "" {
+			return compareParts(a, b, taxonomy.OrderKey)
+		}
+		switch taxonomy.Sort {
+		case "title":
+			return strings.Compare(a.Frontmatter.Title, b.Frontmatter.Title)
+		case "date_asc":
+			return strings.Compare(postDay(a), postDay(b))
+		}
+		return strings.Compare(postDay(b), postDay(a))
+	})
+}
+
+// compareParts orders the parts of a series, the posts with an order
+// first by their order, then the others by date.
+func compareParts(a, b models.Post, orderKey string) int {
+	orderA, okA := partOrder(a, orderKey)
+	orderB, okB := partOrder(b, orderKey)
+	switch {
+	case okA && okB && orderA != orderB:
+		return cmp.Compare(orderA, orderB)
+	case okA && !okB:
+		return -1
+	case !okA && okB:
+		return 1
+	}
+	return strings.Compare(postDay(a), postDay(b))
+}
+
+// partOrder returns the number under orderKey in the front matter of post,
+// if there is one.
+func partOrder(post models.Post, orderKey string) (float64, bool) {
+	value, ok := post.Frontmatter.Extras[orderKey]
+	if !ok || value == nil {
+		return 0, false
+	}
+	order, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(value)), 64)
+	return order, err == nil
+}
+
+// postDay returns the date of post without its time.
+func postDay(post models.Post) string {
+	return post.Frontmatter.Date[:min(len(post.Frontmatter.Date), 10)]
+}
+
+func taxonomyJobs(ssg *models.SSG, name string, taxonomy models.Taxonomy) []RenderJob {
+	config := &ssg.Config
+	themes := models.ThemeCombo{
+		Default:   config.Blog.Themes["default"],
+		Secondary: config.Blog.Themes["secondary"],
+	}
+	templateConfig := models.SSG_CONFIG{
+		Blog:      config.Blog,
+		AdminMode: config.AdminMode,
+	}
+	templatePath := taxonomy.Template
+	if templatePath == "" {
+		templatePath = config.Blog.DefaultFeedTemplate
+	}
+
+	feeds := Terms(ssg, taxonomy)
+	jobs := []RenderJob{}
+	for _, feed := range feeds {
+		dir := filepath.Join(config.Blog.OutputDir, strings.TrimPrefix(feed.Slug, config.Blog.PrefixURL))
+		context := models.TemplateContext{Themes: themes, Config: templateConfig}
+		jobs = append(jobs, FeedPageJobs(ssg, templatePath, feed, taxonomy.PageSize, dir, context)...)
+	}
+	if taxonomy.IndexPath == "" || len(feeds) == 0 {
+		return jobs
+	}
+
+	// the index lists every term page, it changes with any of them
+	index := models.Feed{
+		Title: name,
+		Type:  name,
+		Slug:  config.Blog.PrefixURL + taxonomy.IndexPath,
+	}
+	keys := []string{FeedKey(ssg, taxonomy.IndexTemplate, index)}
+	for _, feed := range feeds {
+		keys = append(keys, FeedKey(ssg, taxonomy.IndexTemplate, feed))
+	}
+	return append(jobs, RenderJob{
+		Template: taxonomy.IndexTemplate,
+		Context: models.TemplateContext{
+			FeedPosts: feeds,
+			Themes:    themes,
+			FeedInfo:  index,
+			Config:    templateConfig,
+		},
+		OutputPath: filepath.Join(config.Blog.OutputDir, taxonomy.IndexPath, "index.html"),
+		Key:        cache.Hash(keys...),
+	})
+}
+
+func init() {
+	RegisterPlugin("Taxonomies", reflect.TypeOf(TaxonomiesPlugin{
+		PluginName: "Taxonomies",
+	}))
+}
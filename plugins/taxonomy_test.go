@@ -0,0 +1,123 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+func taxonomyPost(title, date, status string, extras map[string]any, tags ...string) models.Post {
+	post := models.Post{}
+	post.Frontmatter.Title = title
+	post.Frontmatter.Slug = Slugify(title)
+	post.Frontmatter.Date = date
+	post.Frontmatter.Status = status
+	post.Frontmatter.Tags = tags
+	post.Frontmatter.Extras = extras
+	return post
+}
+
+// termList lists the feeds as "slug: title, title", a line per feed.
+func termList(feeds []models.Feed) string {
+	lines := []string{}
+	for _, feed := range feeds {
+		titles := []string{}
+		for _, post := range feed.Posts {
+			titles = append(titles, post.Frontmatter.Title)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", feed.Slug, strings.Join(titles, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestTerms(t *testing.T) {
+	posts := []models.Post{
+		taxonomyPost("B", "2023-01-02", "", map[string]any{"topic": "Go"}, "go", "web"),
+		taxonomyPost("A", "2024-03-01T10:00:00Z", "published", map[string]any{"topic": []any{"Go", "CLI/TUI", "Go"}}, "go"),
+		taxonomyPost("C", "2023-06-01", "", nil, "web", "go", "go"),
+		taxonomyPost("Draft", "2024-05-01", "draft", map[string]any{"topic": "Go"}, "go", "draft-only"),
+	}
+	tests := []struct {
+		name     string
+		taxonomy models.Taxonomy
+		want     string
+	}{
+		{
+			"by name, newest first",
+			models.Taxonomy{Key: "tags", Path: "tags/{term}"},
+			"blog/tags/go: A, C, B\nblog/tags/web: C, B",
+		},
+		{
+			"by count, oldest first",
+			models.Taxonomy{Key: "tags", Path: "tags/{term}", TermSort: "count", Sort: "date_asc"},
+			"blog/tags/go: B, C, A\nblog/tags/web: B, C",
+		},
+		{
+			"by name descending, by title",
+			models.Taxonomy{Key: "tags", Path: "t/{term}/", TermSort: "name_desc", Sort: "title"},
+			"blog/t/web/: B, C\nblog/t/go/: A, B, C",
+		},
+		{
+			"front matter list, a term which is no directory name",
+			models.Taxonomy{Key: "topic", Path: "topics/{term}"},
+			"blog/topics/cli-tui: A\nblog/topics/Go: A, B",
+		},
+		{
+			"slugified",
+			models.Taxonomy{Key: "topic", Path: "topics/{term}", Slugify: true},
+			"blog/topics/cli-tui: A\nblog/topics/go: A, B",
+		},
+		{
+			"year",
+			models.Taxonomy{Key: "year", Path: "{term}", TermSort: "name_desc"},
+			"blog/2024: A\nblog/2023: C, B",
+		},
+		{
+			"no terms",
+			models.Taxonomy{Key: "missing", Path: "missing/{term}"},
+			"",
+		},
+	}
+	for _, test := range tests {
+		ssg := &models.SSG{Posts: posts}
+		ssg.Config.Blog.PrefixURL = "blog/"
+		if got := termList(Terms(ssg, test.taxonomy)); got != test.want {
+			t.Errorf("%s: Terms() =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+// TestConfigTermURLs pins the URLs of the term pages of ssg.json. The tags
+// and the years keep their terms as they are, the series are slugified as
+// the series pages were before the taxonomies.
+func TestConfigTermURLs(t *testing.T) {
+	data, err := os.ReadFile("../" + models.SSG_CONFIG_FILE_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config models.SSG_CONFIG
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	ssg := &models.SSG{Config: config, Posts: []models.Post{
+		taxonomyPost("Part one", "2023-02-01", "", map[string]any{"series": []any{"Django Basics"}}, "Go", "web dev"),
+		taxonomyPost("Part two", "2024-02-01", "", map[string]any{"series": []any{"Django Basics", "100 Days of Go"}}, "Go"),
+	}}
+	want := map[string]string{
+		"tags":   "tags/Go: Part two, Part one\ntags/web dev: Part one",
+		"series": "series/100-days-of-go: Part two\nseries/django-basics: Part one, Part two",
+		"years":  "2024: Part two\n2023: Part one",
+	}
+	for name, taxonomy := range config.Taxonomies {
+		if got := termList(Terms(ssg, taxonomy)); got != want[name] {
+			t.Errorf("%s: Terms() =\n%s\nwant\n%s", name, got, want[name])
+		}
+	}
+	if len(config.Taxonomies) != len(want) {
+		t.Errorf("ssg.json has %d taxonomies, want %d", len(config.Taxonomies), len(want))
+	}
+}
@@ -0,0 +1,119 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+// ThemeStylesheet is the stylesheet generated from the themes of the
+// config, added to the static files.
+const ThemeStylesheet = "theme.css"
+
+// themeProperties are the custom properties every theme defines, which
+// the templates use like var(--link-hover).
+var themeProperties = []struct {
+	name  string
+	color func(theme models.Theme) string
+}{
+	{"--bg-color", func(t models.Theme) string { return t.Bg }},
+	{"--text-color", func(t models.Theme) string { return t.Text }},
+	{"--secondary-text-color", func(t models.Theme) string { return t.SecondaryText }},
+	{"--link-normal", func(t models.Theme) string { return t.Link.Normal }},
+	{"--link-hover", func(t models.Theme) string { return t.Link.Hover }},
+	{"--link-active", func(t models.Theme) string { return t.Link.Active }},
+	{"--quote-color", func(t models.Theme) string { return t.Quotes }},
+	{"--code-bg-color", func(t models.Theme) string { return t.CodeBlocks.Bg }},
+	{"--code-border-color", func(t models.Theme) string { return t.CodeBlocks.Border }},
+	{"--code-text-color", func(t models.Theme) string { return t.Code.Text }},
+	{"--code-comment-color", func(t models.Theme) string { return t.Code.Comment }},
+	{"--code-keyword-color", func(t models.Theme) string { return t.Code.Keyword }},
+	{"--code-string-color", func(t models.Theme) string { return t.Code.String }},
+	{"--code-number-color", func(t models.Theme) string { return t.Code.Number }},
+	{"--code-variable-color", func(t models.Theme) string { return t.Code.Variable }},
+	{"--code-function-color", func(t models.Theme) string { return t.Code.Function }},
+}
+
+// ThemeNames returns the names of the themes, the default theme first and
+// the others sorted.
+func ThemeNames(themes map[string]models.Theme) []string {
+	names := []string{}
+	for name := range themes {
+		if name != "default" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	if _, ok := themes["default"]; ok {
+		names = append([]string{"default"}, names...)
+	}
+	return names
+}
+
+// ColorScheme returns the color-scheme of theme, light or dark, guessed
+// from the lightness of its background when it does not set one.
+func ColorScheme(theme models.Theme) string {
+	if theme.ColorScheme != "" {
+		return theme.ColorScheme
+	}
+	hex := strings.TrimPrefix(theme.Bg, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return "light"
+	}
+	r, g, b := float64(rgb>>16), float64(rgb>>8&0xff), float64(rgb&0xff)
+	if 0.299*r+0.587*g+0.114*b < 128 {
+		return "dark"
+	}
+	return "light"
+}
+
+// ThemeCSS returns the stylesheet defining the colours of every theme as
+// custom properties. The first theme is the one of the page, another is
+// picked with the data-theme attribute, like <html data-theme="secondary">,
+// and until one is the first theme of the other color-scheme is used for
+// readers whose prefers-color-scheme asks for it. Every theme sets
+// --theme-name, and :root lists them all in --theme-names, for the theme
+// switch.
+func ThemeCSS(themes map[string]models.Theme) []byte {
+	names := ThemeNames(themes)
+	var buffer bytes.Buffer
+	if len(names) == 0 {
+		return buffer.Bytes()
+	}
+	fmt.Fprintf(&buffer, ":root {\n    --theme-names: %s;\n}\n", strings.Join(names, " "))
+	writeTheme(&buffer, fmt.Sprintf(":root, [data-theme=%q]", names[0]), "", names[0], themes[names[0]])
+	for _, name := range names[1:] {
+		writeTheme(&buffer, fmt.Sprintf("[data-theme=%q]", name), "", name, themes[name])
+	}
+	scheme := ColorScheme(themes[names[0]])
+	for _, name := range names[1:] {
+		if other := ColorScheme(themes[name]); other != scheme {
+			fmt.Fprintf(&buffer, "@media (prefers-color-scheme: %s) {\n", other)
+			writeTheme(&buffer, ":root:not([data-theme])", "    ", name, themes[name])
+			buffer.WriteString("}\n")
+			break
+		}
+	}
+	return buffer.Bytes()
+}
+
+func writeTheme(buffer *bytes.Buffer, selector, indent, name string, theme models.Theme) {
+	fmt.Fprintf(buffer, "%s%s {\n", indent, selector)
+	fmt.Fprintf(buffer, "%s    --theme-name: %s;\n", indent, name)
+	fmt.Fprintf(buffer, "%s    color-scheme: %s;\n", indent, ColorScheme(theme))
+	for _, property := range themeProperties {
+		// the properties a theme leaves out keep those of the first one
+		if color := property.color(theme); color != "" {
+			fmt.Fprintf(buffer, "%s    %s: %s;\n", indent, property.name, color)
+		}
+	}
+	fmt.Fprintf(buffer, "%s}\n", indent)
+}
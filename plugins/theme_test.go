@@ -0,0 +1,190 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/models"
+)
+
+func TestColorScheme(t *testing.T) {
+	tests := []struct {
+		theme models.Theme
+		want  string
+	}{
+		{models.Theme{Bg: "#ffffff"}, "light"},
+		{models.Theme{Bg: "#1e1e1e"}, "dark"},
+		{models.Theme{Bg: "#222"}, "dark"},
+		{models.Theme{Bg: "#f5f5dc"}, "light"},
+		{models.Theme{Bg: "#1e1e1e", ColorScheme: "light"}, "light"},
+		{models.Theme{Bg: "black"}, "light"},
+		{models.Theme{}, "light"},
+	}
+	for _, test := range tests {
+		if got := ColorScheme(test.theme); got != test.want {
+			t.Errorf("ColorScheme(%+v) = %q, want %q", test.theme, got, test.want)
+		}
+	}
+}
+
+func TestThemeCSS(t *testing.T) {
+	theme := func(bg, text, comment string) models.Theme {
+		theme := models.Theme{Bg: bg, Text: text}
+		theme.Code.Comment = comment
+		return theme
+	}
+	tests := []struct {
+		name   string
+		themes map[string]models.Theme
+		want   string
+	}{
+		{"no themes", nil, ""},
+		{
+			"one theme",
+			map[string]models.Theme{"default": theme("#fff", "#000", "#777")},
+			`:root {
+    --theme-names: default;
+}
+:root, [data-theme="default"] {
+    --theme-name: default;
+    color-scheme: light;
+    --bg-color: #fff;
+    --text-color: #000;
+    --code-comment-color: #777;
+}
+`,
+		},
+		{
+			"a dark theme for dark readers",
+			map[string]models.Theme{
+				"sepia":     theme("#f5f5dc", "#333", ""),
+				"secondary": theme("#1e1e1e", "#eee", "#999"),
+				"default":   theme("#fff", "#000", "#777"),
+			},
+			`:root {
+    --theme-names: default secondary sepia;
+}
+:root, [data-theme="default"] {
+    --theme-name: default;
+    color-scheme: light;
+    --bg-color: #fff;
+    --text-color: #000;
+    --code-comment-color: #777;
+}
+[data-theme="secondary"] {
+    --theme-name: secondary;
+    color-scheme: dark;
+    --bg-color: #1e1e1e;
+    --text-color: #eee;
+    --code-comment-color: #999;
+}
+[data-theme="sepia"] {
+    --theme-name: sepia;
+    color-scheme: light;
+    --bg-color: #f5f5dc;
+    --text-color: #333;
+}
+@media (prefers-color-scheme: dark) {
+    :root:not([data-theme]) {
+        --theme-name: secondary;
+        color-scheme: dark;
+        --bg-color: #1e1e1e;
+        --text-color: #eee;
+        --code-comment-color: #999;
+    }
+}
+`,
+		},
+		{
+			"no theme of the other color-scheme",
+			map[string]models.Theme{
+				"default": theme("#fff", "#000", ""),
+				"paper":   theme("#f5f5dc", "#333", ""),
+			},
+			`:root {
+    --theme-names: default paper;
+}
+:root, [data-theme="default"] {
+    --theme-name: default;
+    color-scheme: light;
+    --bg-color: #fff;
+    --text-color: #000;
+}
+[data-theme="paper"] {
+    --theme-name: paper;
+    color-scheme: light;
+    --bg-color: #f5f5dc;
+    --text-color: #333;
+}
+`,
+		},
+		{
+			"no default theme",
+			map[string]models.Theme{
+				"night": theme("#000", "#fff", ""),
+				"day":   theme("#fff", "#000", ""),
+			},
+			`:root {
+    --theme-names: day night;
+}
+:root, [data-theme="day"] {
+    --theme-name: day;
+    color-scheme: light;
+    --bg-color: #fff;
+    --text-color: #000;
+}
+[data-theme="night"] {
+    --theme-name: night;
+    color-scheme: dark;
+    --bg-color: #000;
+    --text-color: #fff;
+}
+@media (prefers-color-scheme: dark) {
+    :root:not([data-theme]) {
+        --theme-name: night;
+        color-scheme: dark;
+        --bg-color: #000;
+        --text-color: #fff;
+    }
+}
+`,
+		},
+	}
+	for _, test := range tests {
+		if got := string(ThemeCSS(test.themes)); got != test.want {
+			t.Errorf("%s: ThemeCSS() =\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+// TestHighlightProperties checks that the highlight colours of the
+// templates and static pages are custom properties the themes define.
+func TestHighlightProperties(t *testing.T) {
+	defined := map[string]bool{}
+	for _, property := range themeProperties {
+		defined[property.name] = true
+	}
+	templates, err := filepath.Glob("../templates/*.html")
+	if err != nil || len(templates) == 0 {
+		t.Fatalf("no templates: %v", err)
+	}
+	pages, err := filepath.Glob("../static/*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates = append(templates, pages...)
+	rule := regexp.MustCompile(`\.Hljs-\w+ \{ color: var\((--[\w-]+)`)
+	for _, template := range templates {
+		data, err := os.ReadFile(template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range rule.FindAllStringSubmatch(string(data), -1) {
+			if !defined[match[1]] {
+				t.Errorf("%s: %s uses %s, which no theme defines", filepath.Base(template), match[0], match[1])
+			}
+		}
+	}
+}
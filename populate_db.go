@@ -1,12 +1,9 @@
 package main
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 type Metadata struct {
@@ -19,28 +16,9 @@ type Metadata struct {
 	Tags   []string `json:"tags"`
 }
 
-func main() {
-	// Initialize SQLite database
-	db, err := sql.Open("sqlite3", "./data/blog.db")
-	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
-	}
-	defer db.Close()
-
-	// Create sample author
-	_, err = db.Exec("INSERT OR IGNORE INTO authors (username, name, password) VALUES (?, ?, ?)", "admin", "Admin User", "admin123")
-	if err != nil {
-		log.Fatal("Failed to create author:", err)
-	}
-
-	// Get author ID
-	var authorID int
-	err = db.QueryRow("SELECT id FROM authors WHERE username = ?", "admin").Scan(&authorID)
-	if err != nil {
-		log.Fatal("Failed to get author ID:", err)
-	}
-
-	// Create sample posts
+// insertSamplePosts fills the database with a couple of posts to try the
+// editor with.
+func insertSamplePosts(db *DB, authorID int64) error {
 	samplePosts := []struct {
 		Title    string
 		Slug     string
@@ -95,6 +73,5 @@ func main() {
 
 		fmt.Printf("Inserted post: %s\n", post.Title)
 	}
-
-	fmt.Println("Database populated with sample data!")
-}
\ No newline at end of file
+	return nil
+}
@@ -0,0 +1,203 @@
+package shortcode
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// builtins are the shortcodes every engine starts with.
+var builtins = map[string]Handler{
+	"embed":   embedShortcode,
+	"youtube": youtubeShortcode,
+	"tweet":   tweetShortcode,
+	"gist":    gistShortcode,
+	"figure":  figureShortcode,
+	"callout": calloutShortcode,
+	"details": detailsShortcode,
+}
+
+// embedShortcode embeds the page at its url argument, with the HTML of
+// its oEmbed provider when there is one, or an iframe. YouTube videos are
+// always embedded with their iframe, which needs no network.
+//
+//	{{< embed https://youtu.be/id >}}
+func embedShortcode(call *Call) (string, error) {
+	target := call.Get("url", 0)
+	if target == "" {
+		return "", errors.New("missing url argument")
+	}
+	provider, ok := call.engine.Embeds.Provider(target)
+	if !ok {
+		return fmt.Sprintf(`<iframe src="%s" width="600" height="400" frameborder="0"></iframe>`, html.EscapeString(target)), nil
+	}
+	if provider.Name == "youtube" {
+		return youtubeShortcode(call)
+	}
+	// the fallback of gists needs no network
+	var fallback string
+	var err error
+	switch provider.Name {
+	case "gist":
+		fallback, err = gistShortcode(call)
+	default:
+		fallback = linkEmbed(target)
+	}
+	if err != nil {
+		return "", err
+	}
+	return oEmbed(call, target, fallback)
+}
+
+// youtubeShortcode embeds a video from its id or url.
+//
+//	{{< youtube id="dQw4w9WgXcQ" title="A video" >}}
+func youtubeShortcode(call *Call) (string, error) {
+	id := call.Get("id", 0)
+	if id == "" {
+		id = call.Get("url", -1)
+	}
+	id = youtubeID(id)
+	if id == "" {
+		return "", errors.New("missing video id")
+	}
+	title := call.Get("title", 1)
+	if title == "" {
+		title = "YouTube video"
+	}
+	return fmt.Sprintf(`<iframe width="560" height="315" src="https://www.youtube.com/embed/%s" title="%s" frameborder="0" allowfullscreen></iframe>`,
+		url.PathEscape(id), html.EscapeString(title)), nil
+}
+
+// youtubeID returns the video id of a YouTube url, or s itself if it is
+// not a url.
+func youtubeID(s string) string {
+	parsed, err := url.Parse(s)
+	if err != nil || parsed.Host == "" {
+		return s
+	}
+	if v := parsed.Query().Get("v"); v != "" {
+		return v
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	return segments[len(segments)-1]
+}
+
+// tweetShortcode embeds a tweet through its oEmbed provider, falling back
+// to a link to it.
+//
+//	{{< tweet https://x.com/user/status/id >}}
+func tweetShortcode(call *Call) (string, error) {
+	target := call.Get("url", 0)
+	if target == "" {
+		return "", errors.New("missing url argument")
+	}
+	return oEmbed(call, target, linkEmbed(target))
+}
+
+// oEmbed returns the HTML the provider of target gives for it, or fallback
+// with an error wrapping ErrIncomplete when it cannot be had.
+func oEmbed(call *Call, target, fallback string) (string, error) {
+	response, err := call.engine.Embeds.Fetch(target)
+	if err != nil {
+		return fallback, fmt.Errorf("%w: %v", ErrIncomplete, err)
+	}
+	return fmt.Sprintf(`<div class="embed-container">%s</div>`, response.HTML), nil
+}
+
+func linkEmbed(target string) string {
+	return fmt.Sprintf(`<div class="embed-container"><a href="%s" target="_blank">%s</a></div>`,
+		html.EscapeString(target), html.EscapeString(target))
+}
+
+// gistShortcode embeds a gist, or a single file of it.
+//
+//	{{< gist user id file.go >}}
+//	{{< gist https://gist.github.com/user/id >}}
+func gistShortcode(call *Call) (string, error) {
+	user, id, file := call.Get("user", 0), call.Get("id", 1), call.Get("file", 2)
+	if user == "" {
+		user = call.Get("url", -1)
+	}
+	if parsed, err := url.Parse(user); err == nil && parsed.Host != "" {
+		segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+		if len(segments) != 2 {
+			return "", fmt.Errorf("%q is not a gist url", user)
+		}
+		user, id, file = segments[0], segments[1], call.Get("file", 1)
+	}
+	if user == "" || id == "" {
+		return "", errors.New("missing user or id argument")
+	}
+	src := fmt.Sprintf("https://gist.github.com/%s/%s.js", url.PathEscape(user), url.PathEscape(id))
+	if file != "" {
+		src += "?file=" + url.QueryEscape(file)
+	}
+	return fmt.Sprintf(`<script src="%s"></script>`, html.EscapeString(src)), nil
+}
+
+// figureShortcode shows an image with an optional caption and link.
+//
+//	{{< figure src="/img/a.png" alt="..." caption="..." link="..." >}}
+func figureShortcode(call *Call) (string, error) {
+	src := call.Get("src", 0)
+	if src == "" {
+		return "", errors.New("missing src argument")
+	}
+	alt := call.Get("alt", 1)
+	image := fmt.Sprintf(`<img src="%s" alt="%s"`, html.EscapeString(src), html.EscapeString(alt))
+	if title := call.Get("title", -1); title != "" {
+		image += fmt.Sprintf(` title="%s"`, html.EscapeString(title))
+	}
+	image += ` loading="lazy">`
+	if link := call.Get("link", -1); link != "" {
+		image = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link), image)
+	}
+	figure := "<figure>" + image
+	if caption := call.Get("caption", 2); caption != "" {
+		figure += "<figcaption>" + html.EscapeString(caption) + "</figcaption>"
+	}
+	return figure + "</figure>", nil
+}
+
+// calloutShortcode sets its markdown content apart, as a note, tip or
+// warning.
+//
+//	{{< callout type="warning" title="Careful" >}}
+//	Some **markdown**.
+//	{{< /callout >}}
+func calloutShortcode(call *Call) (string, error) {
+	kind := call.Get("type", 0)
+	if kind == "" {
+		kind = "note"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<div class="callout callout-%s">`+"\n", html.EscapeString(kind))
+	if title := call.Get("title", 1); title != "" {
+		fmt.Fprintf(&b, `<p class="callout-title">%s</p>`+"\n", html.EscapeString(title))
+	}
+	// the blank lines keep the content markdown
+	fmt.Fprintf(&b, "\n%s\n\n</div>\n", strings.TrimSpace(call.Inner))
+	return b.String(), nil
+}
+
+// detailsShortcode folds its markdown content under a summary.
+//
+//	{{< details summary="Show the output" >}}
+//	Some **markdown**.
+//	{{< /details >}}
+func detailsShortcode(call *Call) (string, error) {
+	summary := call.Get("summary", 0)
+	if summary == "" {
+		summary = "Details"
+	}
+	open := ""
+	if _, ok := call.Args["open"]; ok || slices.Contains(call.Positional, "open") {
+		open = " open"
+	}
+	return fmt.Sprintf("<details%s>\n<summary>%s</summary>\n\n%s\n\n</details>\n",
+		open, html.EscapeString(summary), strings.TrimSpace(call.Inner)), nil
+}
@@ -0,0 +1,530 @@
+// Package shortcode expands the shortcodes of a post before its markdown
+// is converted.
+//
+// A shortcode is written {{< name arg "positional arg" key=value >}} on its
+// own, or wrapped around content closed by {{< /name >}}. The content may
+// hold other shortcodes. A shortcode is expanded by the template
+// templates/shortcodes/name.html if there is one, else by the Go handler
+// registered for name. Shortcodes inside code, code blocks and code spans,
+// are left as they are, and {{</* name */>}} is written out as
+// {{< name >}}.
+package shortcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mr-destructive/mr-destructive.github.io/cache"
+	"github.com/mr-destructive/mr-destructive.github.io/oembed"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	gmtext "github.com/yuin/goldmark/text"
+)
+
+const (
+	tagOpen  = "{{<"
+	tagClose = ">}}"
+)
+
+// Call is one use of a shortcode.
+type Call struct {
+	Name string
+	// Args are the key=value arguments
+	Args map[string]string
+	// Positional are the other arguments, in order
+	Positional []string
+	// Inner is the expanded content between the tags, empty for a
+	// shortcode without a closing tag
+	Inner string
+	// Paired is set for a shortcode with a closing tag
+	Paired bool
+	File   string
+	Line   int
+
+	engine *Engine
+}
+
+// Get returns the argument key, or the positional argument at position
+// when there is no such key.
+func (c *Call) Get(key string, position int) string {
+	if value, ok := c.Args[key]; ok {
+		return value
+	}
+	if position >= 0 && position < len(c.Positional) {
+		return c.Positional[position]
+	}
+	return ""
+}
+
+// InnerHTML is Inner for templates, which would escape it otherwise.
+func (c *Call) InnerHTML() template.HTML {
+	return template.HTML(c.Inner)
+}
+
+// Handler returns the output of a shortcode, raw HTML or markdown. A
+// handler that could only produce a stand-in, like a link for an embed
+// that could not be fetched, returns it with an error wrapping
+// ErrIncomplete.
+type Handler func(call *Call) (string, error)
+
+// ErrIncomplete is wrapped by the errors of shortcodes whose output is a
+// stand-in, which should not be cached.
+var ErrIncomplete = errors.New("stand-in output")
+
+// Error is a shortcode that could not be expanded.
+type Error struct {
+	File string
+	Line int
+	Name string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: shortcode %q: %v", e.File, e.Line, e.Name, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Engine expands the shortcodes of posts.
+type Engine struct {
+	// Embeds fetches the HTML of embedded links
+	Embeds *oembed.Client
+
+	handlers  map[string]Handler
+	templates *template.Template
+	// Key is the hash of the shortcode templates, the same source expanded
+	// by engines with the same Key gives the same output
+	Key string
+}
+
+// New returns an engine with the built-in shortcodes and the templates
+// found in dir, which may be empty or not exist.
+func New(dir string) (*Engine, error) {
+	e := &Engine{
+		Embeds:    oembed.NewClient(oembed.Config{}, "", false),
+		handlers:  make(map[string]Handler),
+		templates: template.New("shortcodes"),
+	}
+	for name, handler := range builtins {
+		e.handlers[name] = handler
+	}
+	if dir == "" {
+		return e, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if _, err := e.templates.New(name).Parse(string(data)); err != nil {
+			return nil, err
+		}
+	}
+	e.Key, err = cache.HashDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		e.Key, err = "", nil
+	}
+	return e, err
+}
+
+// Register makes handler expand the shortcode name.
+func (e *Engine) Register(name string, handler Handler) {
+	e.handlers[name] = handler
+}
+
+// legacyEmbed is the embed syntax of the older posts, expanded by the
+// embed shortcode.
+var legacyEmbed = regexp.MustCompile(`{%\s*embed\s+(https?://\S+?)\s*%}`)
+
+// rewriteLegacyEmbeds rewrites the legacy embeds of source outside of code
+// as embed shortcodes.
+func rewriteLegacyEmbeds(source []byte) []byte {
+	if !legacyEmbed.Match(source) {
+		return source
+	}
+	code := codeRanges(source)
+	var output bytes.Buffer
+	last := 0
+	for _, match := range legacyEmbed.FindAllSubmatchIndex(source, -1) {
+		if inRanges(code, match[0]) {
+			continue
+		}
+		// the markdown escapes of the url are not undone by goldmark here
+		url := bytes.ReplaceAll(source[match[2]:match[3]], []byte(`\_`), []byte("_"))
+		output.Write(source[last:match[0]])
+		fmt.Fprintf(&output, `%s embed "%s" %s`, tagOpen, url, tagClose)
+		last = match[1]
+	}
+	output.Write(source[last:])
+	return output.Bytes()
+}
+
+// Expand expands the shortcodes of source, read from file where source
+// starts at line. It also returns the byte ranges of the output written
+// by shortcodes, without the content they wrap, whose HTML comes from
+// them rather than from the post. When some shortcodes could only produce
+// a stand-in, the output comes with an error wrapping ErrIncomplete for
+// each of them.
+func (e *Engine) Expand(file string, line int, source []byte) ([]byte, [][2]int, error) {
+	source = rewriteLegacyEmbeds(source)
+	tokens, err := tokenize(file, line, string(source))
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes, _, err := parse(file, tokens, 0, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	incomplete := []error{}
+	output, generated, err := e.expand(nodes, &incomplete)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byte(output), generated, errors.Join(incomplete...)
+}
+
+// expand returns the output of nodes and the ranges of it written by
+// shortcodes.
+func (e *Engine) expand(nodes []node, incomplete *[]error) (string, [][2]int, error) {
+	var output strings.Builder
+	generated := [][2]int{}
+	for _, n := range nodes {
+		if n.tag == nil {
+			output.WriteString(n.text)
+			continue
+		}
+		inner, innerGenerated, err := e.expand(n.children, incomplete)
+		if err != nil {
+			return "", nil, err
+		}
+		call := &Call{
+			Name:       n.tag.name,
+			Args:       n.tag.args,
+			Positional: n.tag.positional,
+			Inner:      inner,
+			Paired:     n.paired,
+			File:       n.tag.file,
+			Line:       n.tag.line,
+			engine:     e,
+		}
+		expanded, err := e.call(call)
+		if errors.Is(err, ErrIncomplete) {
+			*incomplete = append(*incomplete, &Error{File: call.File, Line: call.Line, Name: call.Name, Err: err})
+		} else if err != nil {
+			return "", nil, &Error{File: call.File, Line: call.Line, Name: call.Name, Err: err}
+		}
+		start := output.Len()
+		output.WriteString(expanded)
+		generated = append(generated, wrapped(expanded, inner, innerGenerated, start)...)
+	}
+	return output.String(), generated, nil
+}
+
+// wrapped returns the ranges of the output of a shortcode written by it,
+// from start, when inner is the content it wraps with innerGenerated
+// written by the shortcodes inside. A shortcode that does not keep its
+// content as it is wrote all of its output.
+func wrapped(output, inner string, innerGenerated [][2]int, start int) [][2]int {
+	content := strings.TrimSpace(inner)
+	at := strings.Index(output, content)
+	if content == "" || at < 0 {
+		return [][2]int{{start, start + len(output)}}
+	}
+	end := at + len(content)
+	ranges := [][2]int{{start, start + at}}
+	shift := start + at - strings.Index(inner, content)
+	for _, r := range innerGenerated {
+		r = [2]int{max(r[0]+shift, start+at), min(r[1]+shift, start+end)}
+		if r[0] < r[1] {
+			ranges = append(ranges, r)
+		}
+	}
+	return append(ranges, [2]int{start + end, start + len(output)})
+}
+
+func (e *Engine) call(call *Call) (string, error) {
+	if t := e.templates.Lookup(call.Name); t != nil {
+		var buffer bytes.Buffer
+		err := t.Execute(&buffer, call)
+		return buffer.String(), err
+	}
+	handler, ok := e.handlers[call.Name]
+	if !ok {
+		return "", errors.New("unknown shortcode")
+	}
+	return handler(call)
+}
+
+type tag struct {
+	name        string
+	args        map[string]string
+	positional  []string
+	closing     bool
+	selfClosing bool
+	file        string
+	line        int
+}
+
+// token is either text or a tag.
+type token struct {
+	text string
+	tag  *tag
+}
+
+type node struct {
+	text     string
+	tag      *tag
+	paired   bool
+	children []node
+}
+
+// tokenize splits source into text and shortcode tags, the tags inside
+// code are text.
+func tokenize(file string, line int, source string) ([]token, error) {
+	tokens := []token{}
+	var code [][2]int
+	if strings.Contains(source, tagOpen) {
+		code = codeRanges([]byte(source))
+	}
+	text := strings.Builder{}
+	for offset := 0; offset < len(source); {
+		start := strings.Index(source[offset:], tagOpen)
+		if start < 0 {
+			text.WriteString(source[offset:])
+			break
+		}
+		start += offset
+		text.WriteString(source[offset:start])
+		if inRanges(code, start) {
+			text.WriteString(tagOpen)
+			offset = start + len(tagOpen)
+			continue
+		}
+		tagLine := line + strings.Count(source[:start], "\n")
+		end := strings.Index(source[start:], tagClose)
+		if end < 0 {
+			return nil, &Error{File: file, Line: tagLine, Err: errors.New("shortcode tag is not closed by " + tagClose)}
+		}
+		end += start + len(tagClose)
+		raw := source[start:end]
+		offset = end
+		inner := strings.TrimSpace(raw[len(tagOpen) : len(raw)-len(tagClose)])
+		if strings.HasPrefix(inner, "/*") && strings.HasSuffix(inner, "*/") {
+			text.WriteString(tagOpen + " " + strings.TrimSpace(inner[2:len(inner)-2]) + " " + tagClose)
+			continue
+		}
+		t, err := parseTag(inner)
+		if err != nil {
+			return nil, &Error{File: file, Line: tagLine, Err: err}
+		}
+		t.file, t.line = file, tagLine
+		if text.Len() > 0 {
+			tokens = append(tokens, token{text: text.String()})
+			text.Reset()
+		}
+		tokens = append(tokens, token{tag: t})
+	}
+	if text.Len() > 0 {
+		tokens = append(tokens, token{text: text.String()})
+	}
+	return tokens, nil
+}
+
+// parseTag parses what is between the delimiters of a tag.
+func parseTag(inner string) (*tag, error) {
+	t := &tag{args: make(map[string]string)}
+	if strings.HasPrefix(inner, "/") {
+		t.closing = true
+		inner = strings.TrimSpace(inner[1:])
+	}
+	if strings.HasSuffix(inner, "/") {
+		t.selfClosing = true
+		inner = strings.TrimSpace(inner[:len(inner)-1])
+	}
+	fields, err := splitArgs(inner)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("shortcode tag without a name")
+	}
+	t.name = fields[0]
+	if t.closing && len(fields) > 1 {
+		return nil, fmt.Errorf("closing tag of %q has arguments", t.name)
+	}
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if ok && key != "" && !strings.HasPrefix(field, `"`) {
+			t.args[key] = unquote(value)
+			continue
+		}
+		t.positional = append(t.positional, unquote(field))
+	}
+	return t, nil
+}
+
+// splitArgs splits s at spaces outside of double quotes.
+func splitArgs(s string) ([]string, error) {
+	fields := []string{}
+	var field strings.Builder
+	quoted, escaped, inField := false, false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			field.WriteRune(r)
+			escaped = false
+		case r == '\\' && quoted:
+			field.WriteRune(r)
+			escaped = true
+		case r == '"':
+			field.WriteRune(r)
+			quoted = !quoted
+			inField = true
+		case !quoted && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quoted argument")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		s = s[1 : len(s)-1]
+		return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(s)
+	}
+	return s
+}
+
+// parse builds the nodes of tokens from i on, up to the closing tag of
+// parent if there is one, and returns the index after it.
+func parse(file string, tokens []token, i int, parent *tag) ([]node, int, error) {
+	nodes := []node{}
+	for i < len(tokens) {
+		t := tokens[i]
+		i++
+		if t.tag == nil {
+			nodes = append(nodes, node{text: t.text})
+			continue
+		}
+		if t.tag.closing {
+			if parent != nil && t.tag.name == parent.name {
+				return nodes, i, nil
+			}
+			return nil, i, &Error{File: file, Line: t.tag.line, Name: t.tag.name, Err: errors.New("closing tag without an opening tag")}
+		}
+		n := node{tag: t.tag}
+		if !t.tag.selfClosing && hasClosing(tokens, i, t.tag.name) {
+			children, next, err := parse(file, tokens, i, t.tag)
+			if err != nil {
+				return nil, next, err
+			}
+			n.children, n.paired, i = children, true, next
+		}
+		nodes = append(nodes, n)
+	}
+	if parent != nil {
+		return nil, i, &Error{File: file, Line: parent.line, Name: parent.name, Err: errors.New("closing tag not found")}
+	}
+	return nodes, i, nil
+}
+
+// hasClosing reports whether the tag name opened just before i is closed
+// further on, skipping the pairs of nested tags of the same name.
+func hasClosing(tokens []token, i int, name string) bool {
+	depth := 0
+	for _, t := range tokens[i:] {
+		if t.tag == nil || t.tag.name != name || t.tag.selfClosing {
+			continue
+		}
+		if !t.tag.closing {
+			depth++
+			continue
+		}
+		if depth == 0 {
+			return true
+		}
+		depth--
+	}
+	return false
+}
+
+// codeParser finds the code of the markdown the shortcodes are in.
+var codeParser = goldmark.DefaultParser()
+
+// codeRanges returns the byte ranges of the code of the markdown source:
+// its fenced and indented code blocks and its code spans, as goldmark
+// reads them.
+func codeRanges(source []byte) [][2]int {
+	ranges := [][2]int{}
+	addLines := func(lines *gmtext.Segments) {
+		for i := range lines.Len() {
+			line := lines.At(i)
+			ranges = append(ranges, [2]int{line.Start, line.Stop})
+		}
+	}
+	doc := codeParser.Parse(gmtext.NewReader(source))
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.FencedCodeBlock:
+			if n.Info != nil {
+				ranges = append(ranges, [2]int{n.Info.Segment.Start, n.Info.Segment.Stop})
+			}
+			addLines(n.Lines())
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeBlock:
+			addLines(n.Lines())
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeSpan:
+			for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+				if text, ok := child.(*ast.Text); ok {
+					ranges = append(ranges, [2]int{text.Segment.Start, text.Segment.Stop})
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return ranges
+}
+
+func inRanges(ranges [][2]int, offset int) bool {
+	for _, r := range ranges {
+		if offset >= r[0] && offset < r[1] {
+			return true
+		}
+	}
+	return false
+}
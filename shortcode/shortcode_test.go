@@ -0,0 +1,189 @@
+package shortcode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mr-destructive/mr-destructive.github.io/oembed"
+)
+
+// testEngine returns an engine with the built-ins and the shortcodes echo
+// and box writing out their call, which need no network.
+func testEngine(t *testing.T) *Engine {
+	t.Helper()
+	e, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	echo := func(call *Call) (string, error) {
+		output := "[" + call.Name + " " + strings.Join(call.Positional, ",")
+		if value, ok := call.Args["key"]; ok {
+			output += " key=" + value
+		}
+		if call.Paired {
+			output += " (" + call.Inner + ")"
+		}
+		return output + "]", nil
+	}
+	e.Register("echo", echo)
+	e.Register("box", echo)
+	return e
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"no shortcode", "Just *markdown*.\n", "Just *markdown*.\n"},
+		{"positional and key arguments", `{{< echo a "b c" key="d e" >}}`, "[echo a,b c key=d e]"},
+		{"escaped quote", `{{< echo "say \"hi\"" >}}`, `[echo say "hi"]`},
+		{"paired", "{{< echo >}}inner{{< /echo >}}", "[echo  (inner)]"},
+		{"nested", "{{< box >}}a {{< echo b >}} c{{< /box >}}", "[box  (a [echo b] c)]"},
+		{"nested of the same name", "{{< echo >}}a {{< echo b >}} c{{< /echo >}}", "[echo ]a [echo b ( c)]"},
+		{"self-closing", "{{< echo a />}} {{< echo >}}b{{< /echo >}}", "[echo a] [echo  (b)]"},
+		{"comment", "{{</* echo a */>}}", "{{< echo a >}}"},
+		{"fenced code block", "```\n{{< echo a >}}\n```\n", "```\n{{< echo a >}}\n```\n"},
+		{"tilde fenced code block", "~~~go\n{{< unknown >}}\n~~~\n", "~~~go\n{{< unknown >}}\n~~~\n"},
+		{"unclosed fence", "```\n{{< unknown\n", "```\n{{< unknown\n"},
+		{"code span", "Write `{{< youtube x >}}` to embed.\n", "Write `{{< youtube x >}}` to embed.\n"},
+		{"double backtick code span", "Write ``{{< unknown ` >}}`` here.\n", "Write ``{{< unknown ` >}}`` here.\n"},
+		{"indented code block", "Text.\n\n    {{< unknown >}}\n\nMore.\n", "Text.\n\n    {{< unknown >}}\n\nMore.\n"},
+		{"indented code in a list", "- item\n\n      {{< unknown >}}\n", "- item\n\n      {{< unknown >}}\n"},
+		{"comment in code", "`{{</* echo */>}}`", "`{{</* echo */>}}`"},
+		{"after code", "`code` {{< echo a >}}", "`code` [echo a]"},
+		{"indented paragraph continuation", "Text\n    {{< echo a >}}\n", "Text\n    [echo a]\n"},
+		{"unmatched backtick", "A ` {{< echo a >}}\n", "A ` [echo a]\n"},
+	}
+	e := testEngine(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, _, err := e.Expand("post.md", 1, []byte(test.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(output) != test.want {
+				t.Errorf("Expand(%q) = %q, want %q", test.source, output, test.want)
+			}
+		})
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   int
+		want   string
+	}{
+		{"unknown shortcode", "Text\n\n{{< unknown >}}\n", 3, "unknown shortcode"},
+		{"unclosed tag", "{{< echo a\n", 1, "not closed"},
+		{"closing without opening", "text\n{{< /echo >}}", 2, "closing tag without an opening tag"},
+		{"closing tag with arguments", "{{< echo >}}{{< /echo a >}}", 1, "has arguments"},
+		{"unterminated quote", `{{< echo "a >}}`, 1, "unterminated quoted argument"},
+		{"empty tag", "{{< >}}", 1, "without a name"},
+		{"missing argument", "{{< youtube >}}", 1, "missing video id"},
+	}
+	e := testEngine(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := e.Expand("post.md", 1, []byte(test.source))
+			var shortcodeErr *Error
+			if !errors.As(err, &shortcodeErr) {
+				t.Fatalf("Expand(%q) error = %v, want a shortcode error", test.source, err)
+			}
+			if shortcodeErr.Line != test.line || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Expand(%q) error = %v at line %d, want %q at line %d", test.source, err, shortcodeErr.Line, test.want, test.line)
+			}
+		})
+	}
+}
+
+func TestExpandGenerated(t *testing.T) {
+	e := testEngine(t)
+	source := "a {{< box >}}b {{< echo c >}} d{{< /box >}} e"
+	output, generated, err := e.Expand("post.md", 1, []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := []string{}
+	for _, r := range generated {
+		parts = append(parts, string(output[r[0]:r[1]]))
+	}
+	want := []string{"[box  (", "[echo c]", ")]"}
+	if strings.Join(parts, "|") != strings.Join(want, "|") {
+		t.Errorf("generated parts of %q = %q, want %q", output, parts, want)
+	}
+}
+
+func TestRewriteLegacyEmbeds(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"embed", "{% embed https://example.com/a %}", `{{< embed "https://example.com/a" >}}`},
+		{"markdown escapes", `{%embed https://example.com/a\_b%}`, `{{< embed "https://example.com/a_b" >}}`},
+		{"several", "{% embed https://a.com %}\n\n{% embed https://b.com %}", "{{< embed \"https://a.com\" >}}\n\n{{< embed \"https://b.com\" >}}"},
+		{"not a url", "{% embed example %}", "{% embed example %}"},
+		{"fenced code block", "```\n{% embed https://a.com %}\n```", "```\n{% embed https://a.com %}\n```"},
+		{"code span", "`{% embed https://a.com %}` and {% embed https://b.com %}", "`{% embed https://a.com %}` and {{< embed \"https://b.com\" >}}"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := string(rewriteLegacyEmbeds([]byte(test.source))); got != test.want {
+				t.Errorf("rewriteLegacyEmbeds(%q) = %q, want %q", test.source, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		want []string
+	}{
+		{"", []string{}},
+		{"a b\tc", []string{"a", "b", "c"}},
+		{`a "b c" d="e f"`, []string{"a", `"b c"`, `d="e f"`}},
+		{`"a \"b\""`, []string{`"a \"b\""`}},
+	}
+	for _, test := range tests {
+		got, err := splitArgs(test.args)
+		if err != nil {
+			t.Fatalf("splitArgs(%q): %v", test.args, err)
+		}
+		if strings.Join(got, "|") != strings.Join(test.want, "|") || len(got) != len(test.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", test.args, got, test.want)
+		}
+	}
+}
+
+func TestEmbedOffline(t *testing.T) {
+	e := testEngine(t)
+	e.Embeds = oembed.NewClient(oembed.Config{}, "", true)
+	tests := []struct {
+		name       string
+		source     string
+		want       string
+		incomplete bool
+	}{
+		{"youtube", "{% embed https://www.youtube.com/watch?v=abc %}", `src="https://www.youtube.com/embed/abc"`, false},
+		{"short youtube url", "{{< embed https://youtu.be/abc >}}", `src="https://www.youtube.com/embed/abc"`, false},
+		{"no provider", "{{< embed https://example.com/a >}}", `<iframe src="https://example.com/a"`, false},
+		{"tweet", "{{< embed https://x.com/a/status/1 >}}", `<a href="https://x.com/a/status/1"`, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, _, err := e.Expand("post.md", 1, []byte(test.source))
+			if incomplete := errors.Is(err, ErrIncomplete); incomplete != test.incomplete || err != nil && !incomplete {
+				t.Fatalf("Expand(%q) error = %v, want incomplete %v", test.source, err, test.incomplete)
+			}
+			if !strings.Contains(string(output), test.want) {
+				t.Errorf("Expand(%q) = %q, want it to contain %q", test.source, output, test.want)
+			}
+		})
+	}
+}
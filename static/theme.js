@@ -1,20 +1,37 @@
+// The themes are defined in theme.css, the one picked is set as the
+// data-theme attribute of the page before it is shown, so it does not
+// flash the default theme first.
+const storedTheme = localStorage.getItem('theme');
+if (storedTheme) {
+    document.documentElement.dataset.theme = storedTheme;
+}
+
 document.addEventListener('DOMContentLoaded', () => {
     const themeToggle = document.getElementById('theme-toggle');
-    const body = document.body;
+    if (!themeToggle) {
+        return;
+    }
 
-    const currentTheme = localStorage.getItem('theme') || 'light';
+    const root = document.documentElement;
+    const style = () => getComputedStyle(root);
+    const names = style().getPropertyValue('--theme-names').trim().split(/\s+/).filter(Boolean);
+    const current = () => style().getPropertyValue('--theme-name').trim();
 
-    if (currentTheme === 'secondary') {
-        body.classList.add('secondary-theme');
+    // a stored theme that is no longer defined falls back to the default
+    if (root.dataset.theme && !names.includes(root.dataset.theme)) {
+        delete root.dataset.theme;
+        localStorage.removeItem('theme');
     }
+    themeToggle.checked = names.length > 0 && current() !== names[0];
 
-    themeToggle.addEventListener('click', () => {
-        if (body.classList.contains('secondary-theme')) {
-            body.classList.remove('secondary-theme');
-            localStorage.setItem('theme', 'light');
-        } else {
-            body.classList.add('secondary-theme');
-            localStorage.setItem('theme', 'secondary');
+    // each toggle switches to the next theme, back to the first after the last
+    themeToggle.addEventListener('change', () => {
+        if (names.length === 0) {
+            return;
         }
+        const next = names[(names.indexOf(current()) + 1) % names.length];
+        root.dataset.theme = next;
+        localStorage.setItem('theme', next);
+        themeToggle.checked = next !== names[0];
     });
 });
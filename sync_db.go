@@ -5,35 +5,37 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"regexp"
-	"strings"
+	"path/filepath"
 	"time"
 
 	htmltomarkdown "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"github.com/mr-destructive/mr-destructive.github.io/plugins"
 	_ "github.com/tursodatabase/libsql-client-go/libsql"
 )
 
-func main() {
+// dbSyncCommand exports the posts written in the editor from the Turso
+// database into the posts directory, by default the ones created in the
+// last hour.
+func dbSyncCommand(args []string) error {
+	flags, global := commandFlags("db sync", "")
+	all := flags.Bool("all", false, "export every post instead of the ones created in the last hour")
+	flags.Parse(args)
+	config, err := global.LoadConfig()
+	if err != nil {
+		return err
+	}
 
 	dbURL := os.Getenv("TURSO_DATABASE_NAME")
 	dbAuthToken := os.Getenv("TURSO_DATABASE_AUTH_TOKEN")
 	dbUrl := fmt.Sprintf("%s?authToken=%s", dbURL, dbAuthToken)
-	args := os.Args
-	sync_post := false
-	if len(args) > 1 {
-		if args[1] == "sync_post" {
-			sync_post = true
-		}
-	}
 
 	db, err := sql.Open("libsql", dbUrl)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to open db %s: %s", dbUrl, err)
-		os.Exit(1)
+		return fmt.Errorf("failed to open db %s: %w", dbURL, err)
 	}
 	defer db.Close()
 	var query string
-	if sync_post {
+	if *all {
 		query = "SELECT * FROM posts;"
 	} else {
 		onehourBackTime := time.Now().Add(time.Hour * -1).Format("2006-01-02 15:04:05")
@@ -42,8 +44,7 @@ func main() {
 
 	rows, err := db.Query(query)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to query db %s: %s", dbUrl, err)
-		os.Exit(1)
+		return fmt.Errorf("failed to query db %s: %w", dbURL, err)
 	}
 	defer rows.Close()
 	for rows.Next() {
@@ -65,48 +66,38 @@ func main() {
 			fmt.Println(err)
 		}
 		fmt.Println(id, title, slug, body, created, updated, metadata, authorId)
-		writePostFile(title, slug, bodyMd, metadata, created, updated)
+		err = writePostFile(config.Blog.PostsDir, title, slug, bodyMd, metadata)
+		if err != nil {
+			return err
+		}
 	}
+	return rows.Err()
 }
 
-func writePostFile(title, slug, body, metadataStr, created, updated string) {
+func writePostFile(baseDir, title, slug, body, metadataStr string) error {
 	metadata := make(map[string]interface{})
 	fmt.Println(metadataStr)
 	err := json.Unmarshal([]byte(metadataStr), &metadata)
 	fmt.Println(metadata)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("post %s metadata: %w", slug, err)
 	}
 	var postDir string
-	var baseDir string = "posts/"
-	if val, ok := metadata["post_dir"]; ok {
-		postDir = val.(string)
+	if val, ok := metadata["post_dir"].(string); ok {
+		postDir = val
 	} else {
 		postDir = "posts"
 	}
-	postDir = baseDir + postDir
+	postDir = filepath.Join(baseDir, postDir)
 	//create folder if not exists
-	if _, err := os.Stat(postDir); os.IsNotExist(err) {
-		os.Mkdir(postDir, 0777)
+	if err := os.MkdirAll(postDir, 0777); err != nil {
+		return err
 	}
 	_, ok := metadata["slug"]
 	if !ok {
-		slug = Slugify(title)
+		slug = plugins.Slugify(title)
 	}
-	filePath := fmt.Sprintf("%s/%s.md", postDir, slug)
+	filePath := filepath.Join(postDir, slug+".md")
 	fileContent := fmt.Sprintf("%s\n\n%s", metadataStr, body)
-	os.WriteFile(filePath, []byte(fileContent), 0660)
-
-}
-
-func Slugify(input string) string {
-	reg, err := regexp.Compile("[^a-zA-Z0-9]+")
-	if err != nil {
-		panic(err)
-	}
-	processedString := reg.ReplaceAllString(input, " ")
-	processedString = strings.TrimSpace(processedString)
-	slug := strings.ReplaceAll(processedString, " ", "-")
-	slug = strings.ToLower(slug)
-	return slug
+	return os.WriteFile(filePath, []byte(fileContent), 0660)
 }